@@ -0,0 +1,73 @@
+package di
+
+import (
+	"fmt"
+
+	"github.com/dozm/di/reflectx"
+)
+
+// Hook called after the instance is created, the returned instance replaces the created one.
+// The replaced instance is not disposed by the container.
+type ActivatingHook func(c Container, instance any) (any, error)
+
+// Hook called after the instance is created and activated.
+type ActivatedHook func(c Container, instance any) error
+
+// Hook called before the instance is disposed with its owning scope.
+type ReleaseHook func(instance any)
+
+// Determines if the activation hooks apply to the descriptor,
+// the hooks don't apply to instances and forwarding descriptors.
+func hasActivationHooks(d *Descriptor) bool {
+	return d.Instance == nil && d.Forward == nil &&
+		(len(d.Activating) > 0 || len(d.Activated) > 0 || len(d.Release) > 0)
+}
+
+// Call the hook after each instance of the service is created,
+// the instance returned by the hook replaces the created one.
+func OnActivating[T any](hook func(c Container, instance T) (T, error)) DescriptorOption {
+	return func(d *Descriptor) {
+		checkHookType[T](d)
+		if t := reflectx.TypeOf[T](); !t.AssignableTo(d.ServiceType) {
+			panic(fmt.Errorf("the type '%v' of the hook can not assignable to the service '%v'", t, d.ServiceType))
+		}
+
+		d.Activating = append(d.Activating, func(c Container, instance any) (any, error) {
+			v, _ := instance.(T)
+			return hook(c, v)
+		})
+	}
+}
+
+// Call the hook after each instance of the service is created and activated.
+func OnActivated[T any](hook func(c Container, instance T) error) DescriptorOption {
+	return func(d *Descriptor) {
+		checkHookType[T](d)
+		d.Activated = append(d.Activated, func(c Container, instance any) error {
+			v, _ := instance.(T)
+			return hook(c, v)
+		})
+	}
+}
+
+// Call the hook before each instance of the service is disposed with its owning scope,
+// it's called even if the instance is not disposable.
+func OnRelease[T any](hook func(instance T)) DescriptorOption {
+	return func(d *Descriptor) {
+		checkHookType[T](d)
+		d.Release = append(d.Release, func(instance any) {
+			v, _ := instance.(T)
+			hook(v)
+		})
+	}
+}
+
+func checkHookType[T any](d *Descriptor) {
+	t := reflectx.TypeOf[T]()
+	if d.Instance != nil || d.Forward != nil {
+		panic(fmt.Errorf("the activation hooks don't apply to the service '%v' registered with an instance", d.ServiceType))
+	}
+	if impl := d.ImplementationType(); t != d.ServiceType && !impl.AssignableTo(t) {
+		panic(fmt.Errorf("the implementation '%v' of the service '%v' can not assignable to type '%v'", impl, d.ServiceType, t))
+	}
+}
@@ -0,0 +1,59 @@
+package di
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestActivation(t *testing.T) {
+	events := make([]string, 0)
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{Value: 1} },
+		OnActivating(func(c Container, d *DisposableStruct) (*DisposableStruct, error) {
+			return &DisposableStruct{Value: d.Value + 1}, nil
+		}),
+		OnActivated(func(c Container, d *DisposableStruct) error {
+			events = append(events, "activated")
+			return nil
+		}),
+		OnRelease(func(d *DisposableStruct) {
+			if d.Disposed {
+				t.Error("expect released before disposed")
+			}
+			events = append(events, "released")
+		}),
+	)
+	AddTransient[int](b, func() int { return 1 }, OnRelease(func(i int) { events = append(events, "int released") }))
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	d := Get[*DisposableStruct](scope.Container())
+	_ = Get[*DisposableStruct](scope.Container())
+	_ = Get[[]int](scope.Container())
+	if d.Value != 2 || len(events) != 1 {
+		t.Error("expect the hooks called once per instance")
+	}
+
+	scope.Dispose()
+	if !d.Disposed || len(events) != 3 || events[1] != "int released" || events[2] != "released" {
+		t.Errorf("unexpected events %v", events)
+	}
+}
+
+func TestActivation_Error(t *testing.T) {
+	b := Builder()
+	AddSingleton[int](b, func() int { return 1 }, OnActivated(func(c Container, i int) error {
+		return errors.New("failed")
+	}))
+
+	if _, err := TryGet[int](b.Build()); err == nil || err.Error() != "failed" {
+		t.Error("expect the error of the hook")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expect a panic for the hook type not assignable")
+		}
+	}()
+	AddSingleton[int](b, func() int { return 1 }, OnRelease(func(s string) {}))
+}
@@ -0,0 +1,85 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/dozm/di/reflectx"
+)
+
+// Create a descriptor of the assisted factory of type factoryType,
+// each parameter of the factory supplies the first unmatched parameter of the constructor with the same type,
+// and the rest of the parameters of the constructor are resolved from the container.
+func NewAssistedDescriptor(factoryType reflect.Type, ctor any) *Descriptor {
+	ci, err := newConstructorInfo(ctor)
+	if err == nil {
+		err = checkAssistedFactory(factoryType, ci)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	assisted := make([]int, factoryType.NumIn())
+	matched := make([]bool, len(ci.In))
+	for j := range assisted {
+		i := matchAssistedParameter(ci, matched, factoryType.In(j))
+		if i < 0 {
+			panic(fmt.Errorf("the parameter '%v' of the assisted factory '%v' doesn't match any parameter of the constructor '%v'",
+				factoryType.In(j), factoryType, ci.FuncType))
+		}
+		matched[i] = true
+		assisted[j] = i
+	}
+
+	return &Descriptor{
+		ServiceType: factoryType,
+		Lifetime:    Lifetime_Transient,
+		Ctor:        ci,
+		Assisted:    assisted,
+	}
+}
+
+func checkAssistedFactory(factoryType reflect.Type, ctor *ConstructorInfo) error {
+	if factoryType.Kind() != reflect.Func || factoryType.IsVariadic() ||
+		(factoryType.NumOut() == 0 || factoryType.NumOut() > 2) ||
+		(factoryType.NumOut() == 2 && !reflectx.IsErrorType(factoryType.Out(1))) {
+		return fmt.Errorf("the assisted factory '%v' must be a function returns a service and an optional error", factoryType)
+	}
+
+	if err := checkConstructor(ctor, factoryType.Out(0)); err != nil {
+		return err
+	}
+
+	if ctor.ReturnsError() && factoryType.NumOut() == 1 {
+		return fmt.Errorf("the assisted factory '%v' must return an error as the constructor does", factoryType)
+	}
+	return nil
+}
+
+// the index of the first unmatched parameter of the constructor with the type t, -1 if not found.
+func matchAssistedParameter(ctor *ConstructorInfo, matched []bool, t reflect.Type) int {
+	for i, in := range ctor.In {
+		if !matched[i] && in == t && ctor.InObject(i) == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// New an assisted factory descriptor.
+// F is the factory function type whose parameters are supplied by the caller,
+// ctor is the constructor whose parameters not supplied by F are resolved from the container.
+// The factory is transient, the services are resolved from the scope that it's injected from.
+//
+//	di.AddAssisted[func(userID string) *Session](b, func(repo *Repo, userID string) *Session { ... })
+func Assisted[F any](ctor any, opts ...DescriptorOption) *Descriptor {
+	return NewAssistedDescriptor(reflectx.TypeOf[F](), ctor).apply(opts)
+}
+
+// Add an assisted factory descriptor to the ContainerBuilder.
+// F is the factory function type whose parameters are supplied by the caller,
+// cb is the ContainerBuilder,
+// ctor is the constructor whose parameters not supplied by F are resolved from the container.
+func AddAssisted[F any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.Add(Assisted[F](ctor, opts...))
+}
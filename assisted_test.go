@@ -0,0 +1,116 @@
+package di
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type assistedSession struct {
+	repo   *DisposableStruct
+	userID string
+	age    int
+}
+
+func newAssistedSession(userID string, repo *DisposableStruct, age int) *assistedSession {
+	return &assistedSession{repo: repo, userID: userID, age: age}
+}
+
+func TestAssisted(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateOnBuild = true
+	})
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	AddAssisted[func(int, string) *assistedSession](b, newAssistedSession)
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	newSession := Get[func(int, string) *assistedSession](scope.Container())
+
+	s := newSession(18, "u1")
+	if s.userID != "u1" || s.age != 18 || s.repo != Get[*DisposableStruct](scope.Container()) {
+		t.Error("assertion failed")
+	}
+
+	scope.Dispose()
+	if !s.repo.Disposed {
+		t.Error("expect disposed with the scope")
+	}
+}
+
+func TestAssisted_Error(t *testing.T) {
+	b := Builder()
+	AddAssisted[func(string) (*assistedSession, error)](b, func(userID string) (*assistedSession, error) {
+		return nil, errors.New("failed")
+	})
+
+	newSession := Get[func(string) (*assistedSession, error)](b.Build())
+	if _, err := newSession("u1"); err == nil {
+		t.Error("expect the error of the constructor")
+	}
+}
+
+func TestAssisted_Validate(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateOnBuild = true
+	})
+	AddAssisted[func(string, int) *assistedSession](b, newAssistedSession)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect a panic for the container parameter not registered")
+			}
+		}()
+		b.Build()
+	}()
+
+	for _, register := range []func(){
+		func() { AddAssisted[func(bool) *assistedSession](b, newAssistedSession) },
+		func() { AddAssisted[func(string) string](b, newAssistedSession) },
+		func() {
+			AddAssisted[func(string) *assistedSession](b, func(string) (*assistedSession, error) { return nil, nil })
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expect a panic for the invalid assisted factory")
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+type assistedConsumer struct {
+	session *assistedSession
+}
+
+func TestAssisted_CallDuringConstruction(t *testing.T) {
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	AddAssisted[func(int, string) *assistedSession](b, newAssistedSession)
+	AddScoped[*assistedConsumer](b, func(newSession func(int, string) *assistedSession) *assistedConsumer {
+		return &assistedConsumer{session: newSession(18, "u1")}
+	})
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+
+	done := make(chan *assistedConsumer)
+	go func() {
+		done <- Get[*assistedConsumer](scope.Container())
+	}()
+
+	select {
+	case consumer := <-done:
+		if consumer.session.repo != Get[*DisposableStruct](scope.Container()) {
+			t.Error("expect the dependencies resolved from the same scope")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect no deadlock on the scope lock")
+	}
+}
@@ -33,6 +33,13 @@ func addServicesWithScoped(cb ContainerBuilder) {
 	cb.Add(Scoped[io.Writer](func() *strings.Builder { return &strings.Builder{} }))
 }
 
+func addServicesWithProvide(cb ContainerBuilder) {
+	Provide2[io.ReadWriter](cb, Lifetime_Transient,
+		func(r io.Reader, w io.Writer) io.ReadWriter { return &readWriter{Reader: r, Writer: w} })
+	Provide0[io.Reader](cb, Lifetime_Transient, func() io.Reader { return strings.NewReader("") })
+	Provide0[io.Writer](cb, Lifetime_Transient, func() io.Writer { return &strings.Builder{} })
+}
+
 func buildContainer(mode string) Container {
 	cb := Builder()
 	cb.ConfigureOptions(func(o *Options) {
@@ -45,6 +52,8 @@ func buildContainer(mode string) Container {
 		addServicesWithFactory(cb)
 	case "constructor":
 		addServicesWithConstructor(cb)
+	case "provide":
+		addServicesWithProvide(cb)
 	case "singleton":
 		addServicesWithSingleton(cb)
 	case "scoped":
@@ -82,6 +91,17 @@ func Benchmark_Constructor(b *testing.B) {
 	}
 }
 
+func Benchmark_Provide(b *testing.B) {
+	c := buildContainer("provide")
+
+	resolve(c)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resolve(c)
+	}
+}
+
 func Benchmark_Singleton(b *testing.B) {
 	c := buildContainer("singleton")
 
@@ -6,20 +6,41 @@ import (
 	"github.com/dozm/di/errorx"
 	"github.com/dozm/di/reflectx"
 	"github.com/dozm/di/syncx"
+	"github.com/dozm/di/util"
 )
 
 type ContainerBuilder interface {
 	Add(...*Descriptor)
+	// Add the descriptors whose service type and key are not registered yet.
+	TryAdd(...*Descriptor)
+	// Add the descriptors unless a descriptor with the same service type, key and implementation is registered,
+	// the constructors and factories are the same implementation if they have the same function code,
+	// e.g. the closures created by the same function literal, and the instances if they're equal.
+	TryAddEnumerable(...*Descriptor)
+	// Replace the last descriptor with the same service type and key, keeping its position.
+	// the descriptor is added if there is no such descriptor.
+	Replace(*Descriptor)
 	// Remove all the descriptors that the service type is t.
 	Remove(t reflect.Type)
 	Contains(t reflect.Type) bool
+	// Decorate all the registered descriptors that the service type is t.
+	// the first input parameter of the decorator is the decorated instance,
+	// the rest are resolved from the Container.
+	Decorate(t reflect.Type, decorator any)
+	// Decorate all the registered descriptors that the service type is t and the service key is key.
+	DecorateKeyed(t reflect.Type, key any, decorator any)
 	Build() Container
 	ConfigureOptions(func(*Options))
+	// Install the modules and the modules they require, each module is installed once.
+	Install(...*Module)
 }
 
 type containerBuilder struct {
 	descriptors          []*Descriptor
 	optionsConfigurators []func(*Options)
+	installedModules     map[string]*Module
+	// the modules being installed, the last one is the current module.
+	installingModules []*Module
 }
 
 func (b *containerBuilder) ConfigureOptions(f func(*Options)) {
@@ -27,14 +48,82 @@ func (b *containerBuilder) ConfigureOptions(f func(*Options)) {
 }
 
 func (b *containerBuilder) Add(d ...*Descriptor) {
-	b.descriptors = append(b.descriptors, d...)
+	for _, descriptor := range b.stampModule(d) {
+		b.descriptors = append(b.descriptors, withForwards(descriptor)...)
+	}
+}
+
+// stamp the descriptors with the name of the module being installed.
+func (b *containerBuilder) stampModule(d []*Descriptor) []*Descriptor {
+	if n := len(b.installingModules); n > 0 {
+		for _, descriptor := range d {
+			if descriptor.Module == "" {
+				descriptor.Module = b.installingModules[n-1].Name
+			}
+		}
+	}
+	return d
+}
+
+func (b *containerBuilder) TryAdd(d ...*Descriptor) {
+	for _, descriptor := range d {
+		if b.lastIndexOf(descriptor.Identifier()) < 0 {
+			b.Add(descriptor)
+		}
+	}
+}
+
+func (b *containerBuilder) TryAddEnumerable(d ...*Descriptor) {
+	for _, descriptor := range d {
+		exists := false
+		for _, existing := range b.descriptors {
+			if existing.Identifier() == descriptor.Identifier() && sameImplementation(existing, descriptor) {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			b.Add(descriptor)
+		}
+	}
+}
+
+func (b *containerBuilder) Replace(d *Descriptor) {
+	i := b.lastIndexOf(d.Identifier())
+	if i < 0 {
+		b.Add(d)
+		return
+	}
+
+	// the forwarding descriptors of the replaced descriptor are replaced by the ones of d.
+	replaced := b.descriptors[i]
+	descriptors := make([]*Descriptor, 0, len(b.descriptors))
+	for j, existing := range b.descriptors {
+		if j == i {
+			descriptors = append(descriptors, withForwards(b.stampModule([]*Descriptor{d})[0])...)
+		} else if existing.Forward != replaced {
+			descriptors = append(descriptors, existing)
+		}
+	}
+	b.descriptors = descriptors
+}
+
+func (b *containerBuilder) lastIndexOf(id ServiceIdentifier) int {
+	for i := len(b.descriptors) - 1; i >= 0; i-- {
+		if b.descriptors[i].Identifier() == id {
+			return i
+		}
+	}
+	return -1
 }
 
 func (b *containerBuilder) Remove(t reflect.Type) {
 	descriptors := b.descriptors
 	j := 0
 	for _, d := range descriptors {
-		if d.ServiceType != t {
+		// the forwarding descriptors are removed with the descriptors they resolve to.
+		if d.ServiceType != t && (d.Forward == nil || d.Forward.ServiceType != t) {
 			descriptors[j] = d
 			j++
 		}
@@ -51,12 +140,53 @@ func (b *containerBuilder) Contains(t reflect.Type) bool {
 	return false
 }
 
+func (b *containerBuilder) Decorate(t reflect.Type, decorator any) {
+	b.DecorateKeyed(t, nil, decorator)
+}
+
+func (b *containerBuilder) DecorateKeyed(t reflect.Type, key any, decorator any) {
+	ci, err := newDecoratorInfo(t, decorator)
+	if err != nil {
+		panic(err)
+	}
+
+	decorated := false
+	for i, d := range b.descriptors {
+		if d.ServiceType == t && d.ServiceKey == key {
+			// copy the descriptor, so the decorator doesn't leak to the other builders.
+			clone := *d
+			clone.Decorators = append(util.ClipSlice(d.Decorators), ci)
+			b.descriptors[i] = &clone
+			b.retarget(d, &clone)
+			decorated = true
+		}
+	}
+
+	if !decorated {
+		panic(&errorx.ServiceNotFound{ServiceType: t, ServiceKey: key})
+	}
+}
+
+// retarget the forwarding descriptors of the descriptor old to the descriptor d.
+func (b *containerBuilder) retarget(old *Descriptor, d *Descriptor) {
+	for i, forward := range b.descriptors {
+		if forward.Forward == old {
+			clone := *forward
+			clone.Forward = d
+			b.descriptors[i] = &clone
+		}
+	}
+}
+
 func (b *containerBuilder) builtInServices(c *container) {
 	csf := c.CallSiteFactory
 
 	csf.Add(ContainerType, &ContainerCallSite{})
 	csf.Add(ScopeFactoryType, newConstantCallSite(ScopeFactoryType, c.Root))
 	csf.Add(IsServiceType, newConstantCallSite(IsServiceType, csf))
+	csf.Add(IsKeyedServiceType, newConstantCallSite(IsKeyedServiceType, csf))
+	csf.Add(LifecycleType, newConstantCallSite(LifecycleType, c.lifecycle))
+	csf.Add(ChildFactoryType, newConstantCallSite(ChildFactoryType, c))
 }
 
 func (b *containerBuilder) configureOptions(options *Options) {
@@ -66,12 +196,23 @@ func (b *containerBuilder) configureOptions(options *Options) {
 }
 
 func (b *containerBuilder) Build() Container {
+	return b.build(nil)
+}
+
+// build the container, the services not registered by b are resolved from the parent container if it's not nil.
+func (b *containerBuilder) build(parent *container) *container {
 	options := DefaultOptions()
 	b.configureOptions(&options)
 
 	c := &container{
-		CallSiteFactory:  newCallSiteFactory(b.descriptors),
-		realizedServices: syncx.NewMap[reflect.Type, ServiceAccessor](),
+		realizedServices: syncx.NewMap[ServiceIdentifier, ServiceAccessor](),
+		lifecycle:        newLifecycle(),
+		parent:           parent,
+	}
+	if parent != nil {
+		c.CallSiteFactory = newChildCallSiteFactory(parent.CallSiteFactory, b.descriptors)
+	} else {
+		c.CallSiteFactory = newCallSiteFactory(b.descriptors)
 	}
 
 	c.Root = newEngineScope(c, true)
@@ -80,14 +221,14 @@ func (b *containerBuilder) Build() Container {
 	b.builtInServices(c)
 
 	if options.ValidateScopes {
-		c.callSiteValidator = newCallSiteValidator()
+		c.callSiteValidator = newCallSiteValidator(c.CallSiteFactory)
 	}
 
 	if options.ValidateOnBuild {
 		errs := make([]error, 0)
 		for _, d := range b.descriptors {
 			if e := c.validateService(d); e != nil {
-				errs = append(errs, e)
+				errs = append(errs, annotateModule(d, e))
 			}
 		}
 
@@ -96,6 +237,14 @@ func (b *containerBuilder) Build() Container {
 		}
 	}
 
+	if err := c.warmUp(options); err != nil {
+		// release the singletons built before the failure.
+		if e := c.Root.Dispose(); e != nil {
+			err = &errorx.AggregateError{Errors: []error{err, e}}
+		}
+		panic(err)
+	}
+
 	return c
 }
 
@@ -110,42 +259,110 @@ func Instance[T any](instance any) *Descriptor {
 }
 
 // New a transient constructor descriptor
-func Transient[T any](ctor any) *Descriptor {
-	return NewConstructorDescriptor(reflectx.TypeOf[T](), Lifetime_Transient, ctor)
+func Transient[T any](ctor any, opts ...DescriptorOption) *Descriptor {
+	return NewConstructorDescriptor(reflectx.TypeOf[T](), Lifetime_Transient, ctor).apply(opts)
 }
 
 // New a scoped constructor descriptor
-func Scoped[T any](ctor any) *Descriptor {
-	return NewConstructorDescriptor(reflectx.TypeOf[T](), Lifetime_Scoped, ctor)
+func Scoped[T any](ctor any, opts ...DescriptorOption) *Descriptor {
+	return NewConstructorDescriptor(reflectx.TypeOf[T](), Lifetime_Scoped, ctor).apply(opts)
 }
 
 // New a singleton constructor descriptor
-func Singleton[T any](ctor any) *Descriptor {
-	return NewConstructorDescriptor(reflectx.TypeOf[T](), Lifetime_Singleton, ctor)
+func Singleton[T any](ctor any, opts ...DescriptorOption) *Descriptor {
+	return NewConstructorDescriptor(reflectx.TypeOf[T](), Lifetime_Singleton, ctor).apply(opts)
 }
 
 // Add a transient service descriptor to the ContainerBuilder.
 // T is the service type,
 // cb is the ContainerBuilder,
 // ctor is the constructor of the service T.
-func AddTransient[T any](cb ContainerBuilder, ctor any) {
-	cb.Add(Transient[T](ctor))
+func AddTransient[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.Add(Transient[T](ctor, opts...))
 }
 
 // Add a scoped service descriptor to the ContainerBuilder.
 // T is the service type,
 // cb is the ContainerBuilder,
 // ctor is the constructor of the service T.
-func AddScoped[T any](cb ContainerBuilder, ctor any) {
-	cb.Add(Scoped[T](ctor))
+func AddScoped[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.Add(Scoped[T](ctor, opts...))
 }
 
 // Add a singleton service descriptor to the ContainerBuilder.
 // T is the service type,
 // cb is the ContainerBuilder,
 // ctor is the constructor of the service T.
-func AddSingleton[T any](cb ContainerBuilder, ctor any) {
-	cb.Add(Singleton[T](ctor))
+func AddSingleton[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.Add(Singleton[T](ctor, opts...))
+}
+
+// Add a transient service descriptor to the ContainerBuilder if the service T is not registered.
+func TryAddTransient[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.TryAdd(Transient[T](ctor, opts...))
+}
+
+// Add a scoped service descriptor to the ContainerBuilder if the service T is not registered.
+func TryAddScoped[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.TryAdd(Scoped[T](ctor, opts...))
+}
+
+// Add a singleton service descriptor to the ContainerBuilder if the service T is not registered.
+func TryAddSingleton[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.TryAdd(Singleton[T](ctor, opts...))
+}
+
+// Add an instance service descriptor to the ContainerBuilder if the service T is not registered.
+func TryAddInstance[T any](cb ContainerBuilder, instance any) {
+	cb.TryAdd(Instance[T](instance))
+}
+
+// Add a transient service descriptor to the ContainerBuilder
+// unless the service T is registered with the same constructor.
+func TryAddEnumerableTransient[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.TryAddEnumerable(Transient[T](ctor, opts...))
+}
+
+// Add a scoped service descriptor to the ContainerBuilder
+// unless the service T is registered with the same constructor.
+func TryAddEnumerableScoped[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.TryAddEnumerable(Scoped[T](ctor, opts...))
+}
+
+// Add a singleton service descriptor to the ContainerBuilder
+// unless the service T is registered with the same constructor.
+func TryAddEnumerableSingleton[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.TryAddEnumerable(Singleton[T](ctor, opts...))
+}
+
+// Add an instance service descriptor to the ContainerBuilder
+// unless the service T is registered with an equal instance.
+func TryAddEnumerableInstance[T any](cb ContainerBuilder, instance any) {
+	cb.TryAddEnumerable(Instance[T](instance))
+}
+
+// Replace the last descriptor of the service T with a transient service descriptor,
+// the descriptor is added if the service T is not registered.
+func ReplaceTransient[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.Replace(Transient[T](ctor, opts...))
+}
+
+// Replace the last descriptor of the service T with a scoped service descriptor,
+// the descriptor is added if the service T is not registered.
+func ReplaceScoped[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.Replace(Scoped[T](ctor, opts...))
+}
+
+// Replace the last descriptor of the service T with a singleton service descriptor,
+// the descriptor is added if the service T is not registered.
+func ReplaceSingleton[T any](cb ContainerBuilder, ctor any, opts ...DescriptorOption) {
+	cb.Replace(Singleton[T](ctor, opts...))
+}
+
+// Replace the last descriptor of the service T with an instance service descriptor,
+// the descriptor is added if the service T is not registered.
+func ReplaceInstance[T any](cb ContainerBuilder, instance any) {
+	cb.Replace(Instance[T](instance))
 }
 
 // Add an instance service descriptor to the ContainerBuilder.
@@ -182,3 +399,96 @@ func AddScopedFactory[T any](cb ContainerBuilder, factory Factory) {
 func AddSingletonFactory[T any](cb ContainerBuilder, factory Factory) {
 	cb.Add(SingletonFactory[T](factory))
 }
+
+// New a transient descriptor with the factory function,
+// the error returned by the factory is propagated to the caller.
+func TransientFunc[T any](factory func(Container) (T, error), opts ...DescriptorOption) *Descriptor {
+	return newFuncDescriptor(Lifetime_Transient, factory).apply(opts)
+}
+
+// New a scoped descriptor with the factory function,
+// the error returned by the factory is propagated to the caller.
+func ScopedFunc[T any](factory func(Container) (T, error), opts ...DescriptorOption) *Descriptor {
+	return newFuncDescriptor(Lifetime_Scoped, factory).apply(opts)
+}
+
+// New a singleton descriptor with the factory function,
+// the error returned by the factory is propagated to the caller.
+func SingletonFunc[T any](factory func(Container) (T, error), opts ...DescriptorOption) *Descriptor {
+	return newFuncDescriptor(Lifetime_Singleton, factory).apply(opts)
+}
+
+func newFuncDescriptor[T any](lifetime Lifetime, factory func(Container) (T, error)) *Descriptor {
+	d := NewFactoryFuncDescriptor(reflectx.TypeOf[T](), lifetime, func(c Container) (any, error) {
+		return factory(c)
+	})
+	d.factoryImpl = factory
+	return d
+}
+
+// Add a transient service descriptor with the factory function to the ContainerBuilder.
+func AddTransientFunc[T any](cb ContainerBuilder, factory func(Container) (T, error), opts ...DescriptorOption) {
+	cb.Add(TransientFunc[T](factory, opts...))
+}
+
+// Add a scoped service descriptor with the factory function to the ContainerBuilder.
+func AddScopedFunc[T any](cb ContainerBuilder, factory func(Container) (T, error), opts ...DescriptorOption) {
+	cb.Add(ScopedFunc[T](factory, opts...))
+}
+
+// Add a singleton service descriptor with the factory function to the ContainerBuilder.
+func AddSingletonFunc[T any](cb ContainerBuilder, factory func(Container) (T, error), opts ...DescriptorOption) {
+	cb.Add(SingletonFunc[T](factory, opts...))
+}
+
+// New a keyed descriptor with instance
+func KeyedInstance[T any](key any, instance any) *Descriptor {
+	return NewKeyedInstanceDescriptor(reflectx.TypeOf[T](), key, instance)
+}
+
+// New a keyed transient constructor descriptor
+func KeyedTransient[T any](key any, ctor any, opts ...DescriptorOption) *Descriptor {
+	return NewKeyedConstructorDescriptor(reflectx.TypeOf[T](), key, Lifetime_Transient, ctor).apply(opts)
+}
+
+// New a keyed scoped constructor descriptor
+func KeyedScoped[T any](key any, ctor any, opts ...DescriptorOption) *Descriptor {
+	return NewKeyedConstructorDescriptor(reflectx.TypeOf[T](), key, Lifetime_Scoped, ctor).apply(opts)
+}
+
+// New a keyed singleton constructor descriptor
+func KeyedSingleton[T any](key any, ctor any, opts ...DescriptorOption) *Descriptor {
+	return NewKeyedConstructorDescriptor(reflectx.TypeOf[T](), key, Lifetime_Singleton, ctor).apply(opts)
+}
+
+// Add a keyed transient service descriptor to the ContainerBuilder.
+// T is the service type,
+// key is the service key, it must be comparable,
+// ctor is the constructor of the service T.
+func AddKeyedTransient[T any](cb ContainerBuilder, key any, ctor any, opts ...DescriptorOption) {
+	cb.Add(KeyedTransient[T](key, ctor, opts...))
+}
+
+// Add a keyed scoped service descriptor to the ContainerBuilder.
+// T is the service type,
+// key is the service key, it must be comparable,
+// ctor is the constructor of the service T.
+func AddKeyedScoped[T any](cb ContainerBuilder, key any, ctor any, opts ...DescriptorOption) {
+	cb.Add(KeyedScoped[T](key, ctor, opts...))
+}
+
+// Add a keyed singleton service descriptor to the ContainerBuilder.
+// T is the service type,
+// key is the service key, it must be comparable,
+// ctor is the constructor of the service T.
+func AddKeyedSingleton[T any](cb ContainerBuilder, key any, ctor any, opts ...DescriptorOption) {
+	cb.Add(KeyedSingleton[T](key, ctor, opts...))
+}
+
+// Add a keyed instance service descriptor to the ContainerBuilder.
+// T is the service type,
+// key is the service key, it must be comparable,
+// the instance must be assignable to the service T.
+func AddKeyedInstance[T any](cb ContainerBuilder, key any, instance any) {
+	cb.Add(KeyedInstance[T](key, instance))
+}
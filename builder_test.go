@@ -53,3 +53,81 @@ func TestContainerBuilder_Remove(t *testing.T) {
 		t.Error("assertion failed")
 	}
 }
+
+func TestContainerBuilder_TryAdd(t *testing.T) {
+	b := Builder()
+	AddTransient[int](b, func() int { return 1 })
+	TryAddTransient[int](b, func() int { return 2 })
+	TryAddSingleton[string](b, func() string { return "a" })
+	TryAddInstance[string](b, "b")
+	b.TryAdd(KeyedInstance[string]("key", "c"))
+
+	c := b.Build()
+
+	if v := Get[[]int](c); len(v) != 1 || v[0] != 1 {
+		t.Error("assertion failed")
+	}
+
+	if Get[string](c) != "a" || GetKeyed[string](c, "key") != "c" {
+		t.Error("assertion failed")
+	}
+}
+
+func TestContainerBuilder_TryAddEnumerable(t *testing.T) {
+	newOne := func() int { return 1 }
+	b := Builder()
+	b.TryAddEnumerable(Transient[int](newOne))
+	b.TryAddEnumerable(Singleton[int](newOne))
+	b.TryAddEnumerable(Transient[int](func() int { return 2 }))
+	b.TryAddEnumerable(Instance[int](3), Instance[int](4))
+	TryAddEnumerableInstance[int](b, 3)
+	TryAddEnumerableSingleton[int](b, newOne)
+	TryAddEnumerableScoped[string](b, func() string { return "a" })
+	TryAddEnumerableTransient[string](b, func() string { return "b" })
+
+	c := b.Build()
+	if v := Get[[]int](c); len(v) != 4 || v[0] != 1 || v[1] != 2 || v[2] != 3 || v[3] != 4 {
+		t.Error("assertion failed")
+	}
+	if v := Get[[]string](c); len(v) != 2 {
+		t.Error("assertion failed")
+	}
+}
+
+func TestContainerBuilder_Replace(t *testing.T) {
+	b := Builder()
+	AddTransient[int](b, func() int { return 1 })
+	AddTransient[int](b, func() int { return 2 })
+	AddTransient[int](b, func() int { return 3 })
+	b.Replace(Instance[int](4))
+	b.Replace(Instance[string]("a"))
+
+	c := b.Build()
+
+	if v := Get[[]int](c); len(v) != 3 || v[0] != 1 || v[1] != 2 || v[2] != 4 {
+		t.Error("assertion failed")
+	}
+
+	if Get[int](c) != 4 || Get[string](c) != "a" {
+		t.Error("assertion failed")
+	}
+}
+
+func TestContainerBuilder_ReplaceHelpers(t *testing.T) {
+	b := Builder()
+	AddTransient[int](b, func() int { return 1 })
+	AddTransient[int](b, func() int { return 2 })
+	ReplaceSingleton[int](b, func() int { return 3 })
+	ReplaceScoped[string](b, func() string { return "a" })
+	ReplaceTransient[string](b, func() string { return "b" })
+	ReplaceInstance[bool](b, true)
+
+	c := b.Build()
+	if v := Get[[]int](c); len(v) != 2 || v[0] != 1 || v[1] != 3 {
+		t.Error("assertion failed")
+	}
+
+	if v := Get[[]string](c); len(v) != 1 || v[0] != "b" || !Get[bool](c) {
+		t.Error("assertion failed")
+	}
+}
@@ -10,6 +10,8 @@ const (
 	CacheLocation_Scope
 	CacheLocation_Dispose
 	CacheLocation_None
+	// the result is looked up and stored by the LifetimeManager of the call site.
+	CacheLocation_Managed
 )
 
 var NoneResultCache = newResultCache(CacheLocation_None, EmptyServiceCacheKey)
@@ -18,16 +20,23 @@ type ServiceCacheKey struct {
 	// Type of service being cached
 	ServiceType reflect.Type
 
+	// Key of the service being cached, nil for a non-keyed service.
+	ServiceKey any
+
 	// Reverse index of the service when resolved in slice where default instance gets slot 0.
 	Slot int
 }
 
-var EmptyServiceCacheKey = ServiceCacheKey{nil, 0}
+var EmptyServiceCacheKey = ServiceCacheKey{nil, nil, 0}
 
 // callsite result cache
 type ResultCache struct {
 	Location CacheLocation
 	Key      ServiceCacheKey
+	// the scope that stores the result in the nested scopes if the location is CacheLocation_Scope.
+	ScopeMode ScopeMode
+	// the manager of the result if the location is CacheLocation_Managed.
+	Manager LifetimeManager
 }
 
 func newResultCache(loc CacheLocation, key ServiceCacheKey) ResultCache {
@@ -37,7 +46,11 @@ func newResultCache(loc CacheLocation, key ServiceCacheKey) ResultCache {
 	}
 }
 
-func newResultCacheWithLifetime(lifetime Lifetime, typ reflect.Type, slot int) ResultCache {
+func newServiceCacheKey(id ServiceIdentifier, slot int) ServiceCacheKey {
+	return ServiceCacheKey{id.ServiceType, id.ServiceKey, slot}
+}
+
+func newResultCacheWithLifetime(lifetime Lifetime, id ServiceIdentifier, slot int) ResultCache {
 	loc := CacheLocation_None
 	switch lifetime {
 	case Lifetime_Singleton:
@@ -50,6 +63,14 @@ func newResultCacheWithLifetime(lifetime Lifetime, typ reflect.Type, slot int) R
 
 	return ResultCache{
 		Location: loc,
-		Key:      ServiceCacheKey{typ, slot},
+		Key:      newServiceCacheKey(id, slot),
+	}
+}
+
+func newManagedResultCache(manager LifetimeManager, id ServiceIdentifier, slot int) ResultCache {
+	return ResultCache{
+		Location: CacheLocation_Managed,
+		Key:      newServiceCacheKey(id, slot),
+		Manager:  manager,
 	}
 }
@@ -22,6 +22,14 @@ const (
 	CallSiteKind_Scope
 	CallSiteKind_Transient
 	CallSiteKind_Singleton
+	CallSiteKind_Struct
+	CallSiteKind_Decorator
+	CallSiteKind_Optional
+	CallSiteKind_Lazy
+	CallSiteKind_Provider
+	CallSiteKind_Assisted
+	CallSiteKind_Activation
+	CallSiteKind_ScopeLocal
 )
 
 type CallSite interface {
@@ -75,6 +83,8 @@ type FactoryCallSite struct {
 	value       any
 	cache       ResultCache
 	Factory     Factory
+	// the factory that returns an error, it's set instead of Factory.
+	FactoryFunc FactoryFunc
 }
 
 func (cs *FactoryCallSite) Value() any {
@@ -105,6 +115,14 @@ func newFactoryCallSite(cache ResultCache, serviceType reflect.Type, factory Fac
 	}
 }
 
+func newFactoryFuncCallSite(cache ResultCache, serviceType reflect.Type, factory FactoryFunc) *FactoryCallSite {
+	return &FactoryCallSite{
+		serviceType: serviceType,
+		cache:       cache,
+		FactoryFunc: factory,
+	}
+}
+
 //
 type ConstructorCallSite struct {
 	serviceType reflect.Type
@@ -206,6 +224,311 @@ func newSliceCallSite(cache ResultCache, elem reflect.Type, callSites []CallSite
 	}
 }
 
+// Struct call site, the fields of the struct are resolved by their own call sites.
+type StructCallSite struct {
+	serviceType reflect.Type
+	value       any
+	Struct      *StructInfo
+	// call sites of the fields, nil for an optional field that is not registered.
+	Fields []CallSite
+	cache  ResultCache
+}
+
+func (cs *StructCallSite) Value() any {
+	return cs.value
+}
+
+func (cs *StructCallSite) SetValue(v any) {
+	cs.value = v
+}
+
+func (cs *StructCallSite) ServiceType() reflect.Type {
+	return cs.serviceType
+}
+
+func (cs *StructCallSite) Kind() CallSiteKind {
+	return CallSiteKind_Struct
+}
+
+func (cs *StructCallSite) Cache() ResultCache {
+	return cs.cache
+}
+
+func newStructCallSite(cache ResultCache, serviceType reflect.Type, info *StructInfo, fields []CallSite) *StructCallSite {
+	return &StructCallSite{
+		cache:       cache,
+		serviceType: serviceType,
+		Struct:      info,
+		Fields:      fields,
+	}
+}
+
+// Decorator call site, the decorator is called with the instance resolved by the inner call site.
+type DecoratorCallSite struct {
+	serviceType reflect.Type
+	value       any
+	Inner       CallSite
+	Decorator   *ConstructorInfo
+	// call sites of the input parameters of the decorator except the first one, which is the inner instance.
+	Parameters []CallSite
+	// whether the decorator captures the instance it creates for disposal,
+	// the inner instance returned as it is isn't captured again.
+	Capture bool
+	cache   ResultCache
+}
+
+func (cs *DecoratorCallSite) Value() any {
+	return cs.value
+}
+
+func (cs *DecoratorCallSite) SetValue(v any) {
+	cs.value = v
+}
+
+func (cs *DecoratorCallSite) ServiceType() reflect.Type {
+	return cs.serviceType
+}
+
+func (cs *DecoratorCallSite) Kind() CallSiteKind {
+	return CallSiteKind_Decorator
+}
+
+func (cs *DecoratorCallSite) Cache() ResultCache {
+	return cs.cache
+}
+
+func newDecoratorCallSite(cache ResultCache, serviceType reflect.Type, inner CallSite, decorator *ConstructorInfo, parameters []CallSite) *DecoratorCallSite {
+	return &DecoratorCallSite{
+		cache:       cache,
+		serviceType: serviceType,
+		Inner:       inner,
+		Decorator:   decorator,
+		Parameters:  parameters,
+	}
+}
+
+// Optional call site, the inner call site is nil if the service is not registered.
+type OptionalCallSite struct {
+	serviceType reflect.Type
+	value       any
+	Inner       CallSite
+}
+
+func (cs *OptionalCallSite) Value() any {
+	return cs.value
+}
+
+func (cs *OptionalCallSite) SetValue(v any) {
+	cs.value = v
+}
+
+func (cs *OptionalCallSite) ServiceType() reflect.Type {
+	return cs.serviceType
+}
+
+func (cs *OptionalCallSite) Kind() CallSiteKind {
+	return CallSiteKind_Optional
+}
+
+func (cs *OptionalCallSite) Cache() ResultCache {
+	return NoneResultCache
+}
+
+func newOptionalCallSite(serviceType reflect.Type, inner CallSite) *OptionalCallSite {
+	return &OptionalCallSite{
+		serviceType: serviceType,
+		Inner:       inner,
+	}
+}
+
+// Lazy call site, the service is resolved on demand, so its call site is not created eagerly.
+type LazyCallSite struct {
+	serviceType reflect.Type
+	value       any
+	Service     ServiceIdentifier
+}
+
+func (cs *LazyCallSite) Value() any {
+	return cs.value
+}
+
+func (cs *LazyCallSite) SetValue(v any) {
+	cs.value = v
+}
+
+func (cs *LazyCallSite) ServiceType() reflect.Type {
+	return cs.serviceType
+}
+
+func (cs *LazyCallSite) Kind() CallSiteKind {
+	return CallSiteKind_Lazy
+}
+
+func (cs *LazyCallSite) Cache() ResultCache {
+	return NoneResultCache
+}
+
+func newLazyCallSite(serviceType reflect.Type, service ServiceIdentifier) *LazyCallSite {
+	return &LazyCallSite{
+		serviceType: serviceType,
+		Service:     service,
+	}
+}
+
+// Provider call site, the service is resolved on each call of the provider,
+// so its call site is not created eagerly.
+type ProviderCallSite struct {
+	serviceType reflect.Type
+	value       any
+	Service     ServiceIdentifier
+}
+
+func (cs *ProviderCallSite) Value() any {
+	return cs.value
+}
+
+func (cs *ProviderCallSite) SetValue(v any) {
+	cs.value = v
+}
+
+func (cs *ProviderCallSite) ServiceType() reflect.Type {
+	return cs.serviceType
+}
+
+func (cs *ProviderCallSite) Kind() CallSiteKind {
+	return CallSiteKind_Provider
+}
+
+func (cs *ProviderCallSite) Cache() ResultCache {
+	return NoneResultCache
+}
+
+func newProviderCallSite(serviceType reflect.Type, service ServiceIdentifier) *ProviderCallSite {
+	return &ProviderCallSite{
+		serviceType: serviceType,
+		Service:     service,
+	}
+}
+
+// Assisted call site, the value is a factory function that calls the constructor
+// with its arguments and the services resolved by the parameter call sites.
+type AssistedCallSite struct {
+	serviceType reflect.Type
+	value       any
+	Ctor        *ConstructorInfo
+	// indices of the constructor parameters supplied by the arguments of the factory.
+	Assisted []int
+	// call sites of the input parameters of the constructor, nil for the assisted parameters.
+	Parameters []CallSite
+	cache      ResultCache
+}
+
+func (cs *AssistedCallSite) Value() any {
+	return cs.value
+}
+
+func (cs *AssistedCallSite) SetValue(v any) {
+	cs.value = v
+}
+
+func (cs *AssistedCallSite) ServiceType() reflect.Type {
+	return cs.serviceType
+}
+
+func (cs *AssistedCallSite) Kind() CallSiteKind {
+	return CallSiteKind_Assisted
+}
+
+func (cs *AssistedCallSite) Cache() ResultCache {
+	return cs.cache
+}
+
+func newAssistedCallSite(cache ResultCache, serviceType reflect.Type, ctor *ConstructorInfo, assisted []int, parameters []CallSite) *AssistedCallSite {
+	return &AssistedCallSite{
+		cache:       cache,
+		serviceType: serviceType,
+		Ctor:        ctor,
+		Assisted:    assisted,
+		Parameters:  parameters,
+	}
+}
+
+// Activation call site, the hooks are called with the instance resolved by the inner call site.
+type ActivationCallSite struct {
+	serviceType reflect.Type
+	value       any
+	Inner       CallSite
+	Activating  []ActivatingHook
+	Activated   []ActivatedHook
+	Release     []ReleaseHook
+	cache       ResultCache
+}
+
+func (cs *ActivationCallSite) Value() any {
+	return cs.value
+}
+
+func (cs *ActivationCallSite) SetValue(v any) {
+	cs.value = v
+}
+
+func (cs *ActivationCallSite) ServiceType() reflect.Type {
+	return cs.serviceType
+}
+
+func (cs *ActivationCallSite) Kind() CallSiteKind {
+	return CallSiteKind_Activation
+}
+
+func (cs *ActivationCallSite) Cache() ResultCache {
+	return cs.cache
+}
+
+func newActivationCallSite(cache ResultCache, serviceType reflect.Type, inner CallSite, descriptor *Descriptor) *ActivationCallSite {
+	return &ActivationCallSite{
+		cache:       cache,
+		serviceType: serviceType,
+		Inner:       inner,
+		Activating:  descriptor.Activating,
+		Activated:   descriptor.Activated,
+		Release:     descriptor.Release,
+	}
+}
+
+// Scope-local call site, the service is resolved from the nearest scope that provides it.
+type ScopeLocalCallSite struct {
+	serviceType reflect.Type
+	value       any
+	Service     ServiceIdentifier
+}
+
+func (cs *ScopeLocalCallSite) Value() any {
+	return cs.value
+}
+
+func (cs *ScopeLocalCallSite) SetValue(v any) {
+	cs.value = v
+}
+
+func (cs *ScopeLocalCallSite) ServiceType() reflect.Type {
+	return cs.serviceType
+}
+
+func (cs *ScopeLocalCallSite) Kind() CallSiteKind {
+	return CallSiteKind_ScopeLocal
+}
+
+func (cs *ScopeLocalCallSite) Cache() ResultCache {
+	return NoneResultCache
+}
+
+func newScopeLocalCallSite(service ServiceIdentifier) *ScopeLocalCallSite {
+	return &ScopeLocalCallSite{
+		serviceType: service.ServiceType,
+		Service:     service,
+	}
+}
+
 //
 type chainItem struct {
 	Order int
@@ -213,34 +536,34 @@ type chainItem struct {
 }
 
 type callSiteChain struct {
-	items map[reflect.Type]chainItem
+	items map[ServiceIdentifier]chainItem
 }
 
-func (c *callSiteChain) CheckCircularDependency(serviceType reflect.Type) error {
+func (c *callSiteChain) CheckCircularDependency(id ServiceIdentifier) error {
 	for k := range c.items {
-		if k == serviceType {
-			return c.createCircularDependencyError(serviceType)
+		if k == id {
+			return c.createCircularDependencyError(id)
 		}
 	}
 	return nil
 }
 
-func (c *callSiteChain) Remove(serviceType reflect.Type) {
-	delete(c.items, serviceType)
+func (c *callSiteChain) Remove(id ServiceIdentifier) {
+	delete(c.items, id)
 }
 
-// the ctor can be nil when the serviceType is a slice
-func (c *callSiteChain) Add(serviceType reflect.Type, ctor *ConstructorInfo) {
-	c.items[serviceType] = chainItem{
+// the ctor can be nil when the service type is a slice
+func (c *callSiteChain) Add(id ServiceIdentifier, ctor *ConstructorInfo) {
+	c.items[id] = chainItem{
 		Order: len(c.items),
 		Ctor:  ctor,
 	}
 }
 
-func (c *callSiteChain) createCircularDependencyError(t reflect.Type) error {
+func (c *callSiteChain) createCircularDependencyError(id ServiceIdentifier) error {
 	var sb strings.Builder
 	sb.WriteString("a circular dependency was detected for the service of type '")
-	sb.WriteString(t.String())
+	sb.WriteString(id.String())
 	sb.WriteString("'.")
 	// TODO: add resolution path
 
@@ -249,7 +572,7 @@ func (c *callSiteChain) createCircularDependencyError(t reflect.Type) error {
 
 func newCallSiteChain() *callSiteChain {
 	return &callSiteChain{
-		items: make(map[reflect.Type]chainItem),
+		items: make(map[ServiceIdentifier]chainItem),
 	}
 }
 
@@ -260,8 +583,29 @@ const DefaultSlot int = 0
 type CallSiteFactory struct {
 	descriptors      []*Descriptor
 	callSiteCache    *syncx.Map[ServiceCacheKey, CallSite]
-	descriptorLookup map[reflect.Type]descriptorCacheItem
+	descriptorLookup map[ServiceIdentifier]descriptorCacheItem
 	callSiteLockers  *syncx.LockMap
+	// the descriptors of the call sites created for the services of the modules.
+	moduleDescriptors *syncx.Map[CallSite, *Descriptor]
+	// the factory that a scope-local factory falls back to, nil if the factory is not scope-local.
+	parent *CallSiteFactory
+	// the state of the factory of a child container, nil if the container is not a child.
+	child *childFactory
+}
+
+// the key of an instance created by a scope-local factory or the factory of a child container,
+// so it doesn't collide with the instances created by the other factories in the same scope.
+type childKey struct {
+	owner *CallSiteFactory
+	key   any
+}
+
+// the key that the instances created by the factory are cached with.
+func (f *CallSiteFactory) cacheKey(key ServiceCacheKey) ServiceCacheKey {
+	if f.parent != nil || f.child != nil {
+		key.ServiceKey = childKey{f, key.ServiceKey}
+	}
+	return key
 }
 
 func (f *CallSiteFactory) Descriptors() []*Descriptor {
@@ -270,22 +614,31 @@ func (f *CallSiteFactory) Descriptors() []*Descriptor {
 
 func (f *CallSiteFactory) populate() {
 	for _, descriptor := range f.descriptors {
-		serviceType := descriptor.ServiceType
-		cacheItem := f.descriptorLookup[serviceType]
-		f.descriptorLookup[serviceType] = cacheItem.Add(descriptor)
+		id := descriptor.Identifier()
+		cacheItem := f.descriptorLookup[id]
+		f.descriptorLookup[id] = cacheItem.Add(descriptor)
 	}
 }
 
 func (f *CallSiteFactory) GetCallSite(serviceType reflect.Type, chain *callSiteChain) (CallSite, error) {
-	if site, ok := f.callSiteCache.Load(ServiceCacheKey{ServiceType: serviceType, Slot: DefaultSlot}); ok {
+	return f.GetCallSiteByIdentifier(newServiceIdentifier(serviceType, nil), chain)
+}
+
+func (f *CallSiteFactory) GetCallSiteByIdentifier(id ServiceIdentifier, chain *callSiteChain) (CallSite, error) {
+	if site, ok := f.callSiteCache.Load(newServiceCacheKey(id, DefaultSlot)); ok {
 		return site, nil
 	}
 
-	return f.createCallSite(serviceType, chain)
+	return f.createCallSite(id, chain)
 }
 
 func (f *CallSiteFactory) GetCallSiteByDescriptor(descriptor *Descriptor, chain *callSiteChain) (CallSite, error) {
-	if descriptorCache, ok := f.descriptorLookup[descriptor.ServiceType]; ok {
+	// the descriptor of the parent container shadowed by the child, e.g. the target of a forwarding descriptor.
+	if f.child != nil && f.child.isShadowed(descriptor) {
+		return f.child.base.GetCallSiteByDescriptor(descriptor, chain)
+	}
+
+	if descriptorCache, ok := f.descriptorLookup[descriptor.Identifier()]; ok {
 		return f.tryCreateExact(
 			descriptor,
 			chain,
@@ -296,59 +649,214 @@ func (f *CallSiteFactory) GetCallSiteByDescriptor(descriptor *Descriptor, chain
 
 }
 
-func (f *CallSiteFactory) createCallSite(serviceType reflect.Type, chain *callSiteChain) (CallSite, error) {
-	if err := chain.CheckCircularDependency(serviceType); err != nil {
+func (f *CallSiteFactory) createCallSite(id ServiceIdentifier, chain *callSiteChain) (CallSite, error) {
+	if err := chain.CheckCircularDependency(id); err != nil {
 		return nil, err
 	}
 
-	callSiteLocker := f.callSiteLockers.LoadOrCreate(serviceType)
+	callSiteLocker := f.callSiteLockers.LoadOrCreate(id)
 	callSiteLocker.Lock()
 	defer callSiteLocker.Unlock()
 
-	if descriptor, ok := f.descriptorLookup[serviceType]; ok {
+	if descriptor, ok := f.descriptorLookup[id]; ok {
 		return f.tryCreateExact(descriptor.Last(), chain, DefaultSlot)
 	}
 
-	if serviceType.Kind() == reflect.Slice {
-		return f.createSlice(serviceType, chain)
+	if id.ServiceType.Kind() == reflect.Slice {
+		// the elements registered in the factory shadow the ones of the parent factory.
+		if f.parent != nil && !f.hasDescriptor(newServiceIdentifier(id.ServiceType.Elem(), id.ServiceKey)) {
+			return f.parent.GetCallSiteByIdentifier(id, chain)
+		}
+		return f.createSlice(id, chain)
+	}
+
+	if isOptionalType(id.ServiceType) {
+		return f.createOptional(id, chain)
+	}
+
+	if isLazyType(id.ServiceType) {
+		return f.createLazy(id)
+	}
+
+	if elem, ok := providerElem(id.ServiceType); ok {
+		return f.createProvider(id, elem)
+	}
+
+	if f.parent != nil {
+		return f.parent.GetCallSiteByIdentifier(id, chain)
+	}
+
+	return nil, &errorx.ServiceNotFound{ServiceType: id.ServiceType, ServiceKey: id.ServiceKey}
+}
+
+func (f *CallSiteFactory) createLazy(id ServiceIdentifier) (CallSite, error) {
+	service := newServiceIdentifier(lazyElem(id.ServiceType), id.ServiceKey)
+	if !f.IsKeyedService(service.ServiceType, service.ServiceKey) {
+		return nil, &errorx.ServiceNotFound{ServiceType: service.ServiceType, ServiceKey: service.ServiceKey}
+	}
+
+	return newLazyCallSite(id.ServiceType, service), nil
+}
+
+func (f *CallSiteFactory) createProvider(id ServiceIdentifier, elem reflect.Type) (CallSite, error) {
+	service := newServiceIdentifier(elem, id.ServiceKey)
+	if !f.IsKeyedService(service.ServiceType, service.ServiceKey) {
+		return nil, &errorx.ServiceNotFound{ServiceType: service.ServiceType, ServiceKey: service.ServiceKey}
 	}
 
-	return nil, &errorx.ServiceNotFound{ServiceType: serviceType}
+	return newProviderCallSite(id.ServiceType, service), nil
+}
+
+func (f *CallSiteFactory) createOptional(id ServiceIdentifier, chain *callSiteChain) (CallSite, error) {
+	innerId := newServiceIdentifier(optionalElem(id.ServiceType), id.ServiceKey)
+
+	// the optional is empty if the element is not a service, by the same rule as IsService,
+	// the errors of the element that is a service are reported, e.g. its dependency is not found.
+	var inner CallSite
+	if f.IsKeyedService(innerId.ServiceType, innerId.ServiceKey) {
+		var err error
+		if inner, err = f.GetCallSiteByIdentifier(innerId, chain); err != nil {
+			return nil, err
+		}
+	}
+
+	// cached under the key of the Optional type, so it's created once like the other call sites.
+	callSite := newOptionalCallSite(id.ServiceType, inner)
+	f.callSiteCache.Store(newServiceCacheKey(id, DefaultSlot), callSite)
+	return callSite, nil
 }
 
 func (f *CallSiteFactory) tryCreateExact(descriptor *Descriptor, chain *callSiteChain, slot int) (CallSite, error) {
-	callSiteKey := ServiceCacheKey{descriptor.ServiceType, slot}
+	id := descriptor.Identifier()
+	callSiteKey := newServiceCacheKey(id, slot)
 	callSite, ok := f.callSiteCache.Load(callSiteKey)
 	if ok {
 		return callSite, nil
 	}
 
-	cache := newResultCacheWithLifetime(descriptor.Lifetime, descriptor.ServiceType, slot)
+	if f.child != nil {
+		if callSite, ok := f.child.sharedCallSite(descriptor, chain); ok {
+			f.callSiteCache.Store(callSiteKey, callSite)
+			return callSite, nil
+		}
+	}
+
+	cache := newResultCacheWithLifetime(descriptor.Lifetime, id, slot)
+	if descriptor.LifetimeManager != nil {
+		cache = newManagedResultCache(descriptor.LifetimeManager, id, slot)
+	}
+	cache.Key = f.cacheKey(cache.Key)
+	cache.ScopeMode = descriptor.ScopeMode
+
+	// the result of the outermost decorator is cached in the slot,
+	// the decorated instance is only captured for disposal, and each decorator captures the instance it creates.
+	// the instances of the managed services are captured by their managers.
+	innerCache := cache
+	numDecorators := len(descriptor.Decorators)
+	capture := cache.Location != CacheLocation_Managed
+	if numDecorators > 0 {
+		innerCache = NoneResultCache
+		if capture {
+			innerCache = newResultCache(CacheLocation_Dispose, EmptyServiceCacheKey)
+		}
+	}
+
+	// the instance is cached by the activation call site, so the hooks are called once per instance.
+	activation := hasActivationHooks(descriptor)
+	coreCache := innerCache
+	if activation {
+		coreCache = NoneResultCache
+	}
+
+	callSite, err := f.createDescriptorCallSite(coreCache, id, descriptor, chain)
+	if err != nil {
+		return nil, annotateModule(descriptor, err)
+	}
+
+	if activation {
+		callSite = newActivationCallSite(innerCache, id.ServiceType, callSite, descriptor)
+	}
+
+	for i, decorator := range descriptor.Decorators {
+		decoratorCache := NoneResultCache
+		if i == numDecorators-1 {
+			decoratorCache = cache
+		}
+
+		decoratorCallSite, err := f.createDecoratorCallSite(decoratorCache, id, callSite, decorator, chain)
+		if err != nil {
+			return nil, annotateModule(descriptor, err)
+		}
+		decoratorCallSite.Capture = capture
+		callSite = decoratorCallSite
+	}
+
+	if descriptor.Module != "" {
+		f.moduleDescriptors.Store(callSite, descriptor)
+	}
+	f.callSiteCache.Store(callSiteKey, callSite)
+	return callSite, nil
+}
+
+// the descriptor of the module service that the call site is created for, nil if there is none.
+func (f *CallSiteFactory) moduleDescriptor(callSite CallSite) *Descriptor {
+	if d, ok := f.moduleDescriptors.Load(callSite); ok {
+		return d
+	}
+	if f.child != nil {
+		return f.child.base.moduleDescriptor(callSite)
+	}
+	if f.parent != nil {
+		return f.parent.moduleDescriptor(callSite)
+	}
+	return nil
+}
 
-	var err error
+func (f *CallSiteFactory) createDescriptorCallSite(cache ResultCache, id ServiceIdentifier, descriptor *Descriptor, chain *callSiteChain) (CallSite, error) {
 	if descriptor.Instance != nil {
-		callSite = newConstantCallSite(descriptor.ServiceType, descriptor.Instance)
+		return newConstantCallSite(descriptor.ServiceType, descriptor.Instance), nil
 	} else if descriptor.Factory != nil {
-		callSite = newFactoryCallSite(cache, descriptor.ServiceType, descriptor.Factory)
+		return newFactoryCallSite(cache, descriptor.ServiceType, descriptor.Factory), nil
+	} else if descriptor.FactoryFunc != nil {
+		return newFactoryFuncCallSite(cache, descriptor.ServiceType, descriptor.FactoryFunc), nil
+	} else if descriptor.Assisted != nil {
+		return f.createAssistedCallSite(cache, id, descriptor.Ctor, descriptor.Assisted, chain)
 	} else if descriptor.Ctor != nil {
-		callSite, err = f.createConstructorCallSite(cache, descriptor.ServiceType, descriptor.Ctor, chain)
+		return f.createConstructorCallSite(cache, id, descriptor.Ctor, chain)
+	} else if descriptor.Struct != nil {
+		return f.createStructCallSite(cache, id, descriptor.Struct, chain)
+	} else if descriptor.ScopeLocal {
+		return newScopeLocalCallSite(id), nil
+	} else if descriptor.Forward != nil {
+		// share the call site, so the service types are resolved to the same instance.
+		return f.GetCallSiteByDescriptor(descriptor.Forward, chain)
+	}
+
+	return nil, &errorx.InvalidDescriptor{ServiceType: descriptor.ServiceType}
+}
+
+func (f *CallSiteFactory) createDecoratorCallSite(cache ResultCache, id ServiceIdentifier, inner CallSite, decorator *ConstructorInfo, chain *callSiteChain) (*DecoratorCallSite, error) {
+	chain.Add(id, decorator)
+	defer chain.Remove(id)
+
+	parameters := make([]CallSite, len(decorator.In))
+	for i := 1; i < len(decorator.In); i++ {
+		cs, err := f.createArgumentCallSite(chain, decorator, i)
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		return nil, &errorx.InvalidDescriptor{ServiceType: descriptor.ServiceType}
+		parameters[i] = cs
 	}
 
-	f.callSiteCache.Store(callSiteKey, callSite)
-	return callSite, nil
+	return newDecoratorCallSite(cache, id.ServiceType, inner, decorator, parameters[1:]), nil
 }
 
-func (f *CallSiteFactory) createConstructorCallSite(cache ResultCache, serviceType reflect.Type, ctor *ConstructorInfo, chain *callSiteChain) (*ConstructorCallSite, error) {
-	chain.Add(serviceType, ctor)
-	defer chain.Remove(serviceType)
+func (f *CallSiteFactory) createConstructorCallSite(cache ResultCache, id ServiceIdentifier, ctor *ConstructorInfo, chain *callSiteChain) (*ConstructorCallSite, error) {
+	chain.Add(id, ctor)
+	defer chain.Remove(id)
 
 	if len(ctor.In) == 0 {
-		return newConstructorCallSite(cache, serviceType, ctor, nil), nil
+		return newConstructorCallSite(cache, id.ServiceType, ctor, nil), nil
 	}
 
 	parameterCallSites, err := f.createArgumentCallSites(chain, ctor)
@@ -356,14 +864,78 @@ func (f *CallSiteFactory) createConstructorCallSite(cache ResultCache, serviceTy
 		return nil, err
 	}
 
-	return newConstructorCallSite(cache, serviceType, ctor, parameterCallSites), nil
+	return newConstructorCallSite(cache, id.ServiceType, ctor, parameterCallSites), nil
+}
+
+func (f *CallSiteFactory) createAssistedCallSite(cache ResultCache, id ServiceIdentifier, ctor *ConstructorInfo, assisted []int, chain *callSiteChain) (*AssistedCallSite, error) {
+	chain.Add(id, ctor)
+	defer chain.Remove(id)
+
+	isAssisted := make(map[int]bool, len(assisted))
+	for _, i := range assisted {
+		isAssisted[i] = true
+	}
+
+	parameters := make([]CallSite, len(ctor.In))
+	for i := range ctor.In {
+		if isAssisted[i] {
+			continue
+		}
+		cs, err := f.createArgumentCallSite(chain, ctor, i)
+		if err != nil {
+			return nil, err
+		}
+		parameters[i] = cs
+	}
+
+	return newAssistedCallSite(cache, id.ServiceType, ctor, assisted, parameters), nil
 }
 
 func (f *CallSiteFactory) createArgumentCallSites(chain *callSiteChain, ctor *ConstructorInfo) ([]CallSite, error) {
 	callSites := make([]CallSite, len(ctor.In))
-	for i, t := range ctor.In {
-		cs, err := f.GetCallSite(t, chain)
+	for i := range ctor.In {
+		cs, err := f.createArgumentCallSite(chain, ctor, i)
+		if err != nil {
+			return nil, err
+		}
+		callSites[i] = cs
+	}
+	return callSites, nil
+}
+
+func (f *CallSiteFactory) createArgumentCallSite(chain *callSiteChain, ctor *ConstructorInfo, i int) (CallSite, error) {
+	t := ctor.In[i]
+	if info := ctor.InObject(i); info != nil {
+		fields, err := f.createFieldCallSites(chain, info)
+		if err != nil {
+			return nil, err
+		}
+		return newStructCallSite(NoneResultCache, t, info, fields), nil
+	}
+
+	return f.GetCallSiteByIdentifier(newServiceIdentifier(t, ctor.InKey(i)), chain)
+}
+
+func (f *CallSiteFactory) createStructCallSite(cache ResultCache, id ServiceIdentifier, info *StructInfo, chain *callSiteChain) (*StructCallSite, error) {
+	chain.Add(id, nil)
+	defer chain.Remove(id)
+
+	fields, err := f.createFieldCallSites(chain, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStructCallSite(cache, id.ServiceType, info, fields), nil
+}
+
+func (f *CallSiteFactory) createFieldCallSites(chain *callSiteChain, info *StructInfo) ([]CallSite, error) {
+	callSites := make([]CallSite, len(info.Fields))
+	for i, field := range info.Fields {
+		cs, err := f.GetCallSiteByIdentifier(field.Identifier(), chain)
 		if err != nil {
+			if field.Optional && isServiceNotFound(err, field.Identifier()) {
+				continue
+			}
 			return nil, err
 		}
 		callSites[i] = cs
@@ -371,24 +943,24 @@ func (f *CallSiteFactory) createArgumentCallSites(chain *callSiteChain, ctor *Co
 	return callSites, nil
 }
 
-func (f *CallSiteFactory) createSlice(serviceType reflect.Type, chain *callSiteChain) (CallSite, error) {
-	if serviceType.Kind() != reflect.Slice {
-		return nil, fmt.Errorf("service type '%v' is not slice", serviceType)
+func (f *CallSiteFactory) createSlice(id ServiceIdentifier, chain *callSiteChain) (CallSite, error) {
+	if id.ServiceType.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("service type '%v' is not slice", id.ServiceType)
 	}
 
-	key := ServiceCacheKey{serviceType, DefaultSlot}
+	key := newServiceCacheKey(id, DefaultSlot)
 	if callSite, ok := f.callSiteCache.Load(key); ok {
 		return callSite, nil
 	}
 
-	chain.Add(serviceType, nil)
-	defer chain.Remove(serviceType)
+	chain.Add(id, nil)
+	defer chain.Remove(id)
 
-	elementType := serviceType.Elem()
+	elementType := id.ServiceType.Elem()
 	cacheLocation := CacheLocation_Root
 	callSites := make([]CallSite, 0)
 
-	if descriptorCache, ok := f.descriptorLookup[elementType]; ok {
+	if descriptorCache, ok := f.descriptorLookup[newServiceIdentifier(elementType, id.ServiceKey)]; ok {
 		num := descriptorCache.Num()
 		for i := 0; i < num; i++ {
 			cs, err := f.tryCreateExact(descriptorCache.Get(i), chain, num-i-1)
@@ -403,12 +975,18 @@ func (f *CallSiteFactory) createSlice(serviceType reflect.Type, chain *callSiteC
 
 	resultCache := NoneResultCache
 	if cacheLocation == CacheLocation_Scope || cacheLocation == CacheLocation_Root {
-		resultCache = newResultCache(cacheLocation, key)
+		resultCache = newResultCache(cacheLocation, f.cacheKey(key))
 	}
 
 	return newSliceCallSite(resultCache, elementType, util.ClipSlice(callSites)), nil
 }
 
+func (f *CallSiteFactory) hasDescriptor(id ServiceIdentifier) bool {
+	_, ok := f.descriptorLookup[id]
+	return ok
+}
+
+// the descriptor that the service is resolved from, nil if the service is not registered.
 func (f *CallSiteFactory) Add(serviceType reflect.Type, callSite CallSite) {
 	f.callSiteCache.Store(ServiceCacheKey{ServiceType: serviceType, Slot: DefaultSlot}, callSite)
 }
@@ -419,17 +997,60 @@ func (f *CallSiteFactory) IsService(serviceType reflect.Type) bool {
 		return false
 	}
 
-	if _, ok := f.descriptorLookup[serviceType]; ok {
+	if _, ok := f.descriptorLookup[newServiceIdentifier(serviceType, nil)]; ok {
 		return true
 	}
 
-	if serviceType.Kind() == reflect.Slice {
+	if f.parent != nil && f.parent.IsService(serviceType) {
 		return true
 	}
 
+	if serviceType.Kind() == reflect.Slice || isOptionalType(serviceType) {
+		return true
+	}
+
+	if isLazyType(serviceType) {
+		return f.IsService(lazyElem(serviceType))
+	}
+
+	if elem, ok := providerElem(serviceType); ok {
+		return f.IsService(elem)
+	}
+
 	return serviceType == ContainerType ||
 		serviceType == ScopeFactoryType ||
-		serviceType == IsServiceType
+		serviceType == IsServiceType ||
+		serviceType == IsKeyedServiceType ||
+		serviceType == ChildFactoryType
+}
+
+// Determines if the specified keyed service is available from the ServiceProvider.
+func (f *CallSiteFactory) IsKeyedService(serviceType reflect.Type, key any) bool {
+	if key == nil {
+		return f.IsService(serviceType)
+	}
+
+	if serviceType == nil {
+		return false
+	}
+
+	if _, ok := f.descriptorLookup[newServiceIdentifier(serviceType, key)]; ok {
+		return true
+	}
+
+	if f.parent != nil && f.parent.IsKeyedService(serviceType, key) {
+		return true
+	}
+
+	if isLazyType(serviceType) {
+		return f.IsKeyedService(lazyElem(serviceType), key)
+	}
+
+	if elem, ok := providerElem(serviceType); ok {
+		return f.IsKeyedService(elem, key)
+	}
+
+	return serviceType.Kind() == reflect.Slice || isOptionalType(serviceType)
 }
 
 func (f *CallSiteFactory) getCommonCacheLocation(locationA CacheLocation, locationB CacheLocation) CacheLocation {
@@ -441,14 +1062,25 @@ func (f *CallSiteFactory) getCommonCacheLocation(locationA CacheLocation, locati
 }
 
 func newCallSiteFactory(descriptors []*Descriptor) *CallSiteFactory {
-	d := make([]*Descriptor, len(descriptors))
-	copy(d, descriptors)
+	return newCallSiteFactoryExpanded(expandDescriptors(descriptors))
+}
+
+// expand the result objects and the forwarding descriptors of AsImplementedInterfaces.
+func expandDescriptors(descriptors []*Descriptor) []*Descriptor {
+	d, err := expandResultObjects(descriptors)
+	if err != nil {
+		panic(err)
+	}
+	return expandForwards(d)
+}
 
+func newCallSiteFactoryExpanded(d []*Descriptor) *CallSiteFactory {
 	f := &CallSiteFactory{
-		descriptors:      d,
-		callSiteCache:    syncx.NewMap[ServiceCacheKey, CallSite](),
-		descriptorLookup: make(map[reflect.Type]descriptorCacheItem),
-		callSiteLockers:  &syncx.LockMap{},
+		descriptors:       d,
+		callSiteCache:     syncx.NewMap[ServiceCacheKey, CallSite](),
+		descriptorLookup:  make(map[ServiceIdentifier]descriptorCacheItem),
+		callSiteLockers:   &syncx.LockMap{},
+		moduleDescriptors: syncx.NewMap[CallSite, *Descriptor](),
 	}
 
 	f.populate()
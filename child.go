@@ -0,0 +1,123 @@
+package di
+
+import (
+	"sync"
+)
+
+// The state of the factory of a child container. The descriptors of the child container shadow the ones of
+// the parent container with the same identifiers, the other descriptors are inherited from the parent container.
+// The call sites of the inherited descriptors are rebuilt by the child factory, so their dependencies are resolved
+// to the shadowing services, except the singletons that don't depend on the shadowed services,
+// they're shared with the parent container.
+type childFactory struct {
+	// the factory of the parent container.
+	base *CallSiteFactory
+	// the descriptors registered in the child container.
+	own map[*Descriptor]bool
+	// the identifiers of the services registered in the child container.
+	ownIds map[ServiceIdentifier]bool
+
+	mu sync.Mutex
+	// whether the call sites of the base factory depend on the shadowed services.
+	shadowing map[CallSite]bool
+	// the singleton call sites shared with the parent container.
+	shared map[CallSite]bool
+}
+
+// Create the factory of a child container with the descriptors registered in the child container.
+func newChildCallSiteFactory(base *CallSiteFactory, descriptors []*Descriptor) *CallSiteFactory {
+	child := &childFactory{
+		base:      base,
+		own:       make(map[*Descriptor]bool),
+		ownIds:    make(map[ServiceIdentifier]bool),
+		shadowing: make(map[CallSite]bool),
+		shared:    make(map[CallSite]bool),
+	}
+
+	own := expandDescriptors(descriptors)
+	for _, d := range own {
+		child.own[d] = true
+		child.ownIds[d.Identifier()] = true
+	}
+
+	inherited := make([]*Descriptor, 0, len(base.descriptors)+len(own))
+	for _, d := range base.descriptors {
+		if !child.ownIds[d.Identifier()] {
+			inherited = append(inherited, d)
+		}
+	}
+
+	// the call sites of the shadowed services in the base factory.
+	for id := range child.ownIds {
+		if cacheItem, ok := base.descriptorLookup[id]; ok {
+			for i := 0; i < cacheItem.Num(); i++ {
+				if cs, err := base.GetCallSiteByDescriptor(cacheItem.Get(i), newCallSiteChain()); err == nil {
+					child.shadowing[cs] = true
+				}
+			}
+		}
+	}
+
+	f := newCallSiteFactoryExpanded(append(inherited, own...))
+	f.child = child
+	return f
+}
+
+// Determines if the descriptor of the parent container is shadowed by the child container.
+func (c *childFactory) isShadowed(d *Descriptor) bool {
+	return c.ownIds[d.Identifier()] && !c.own[d]
+}
+
+// The call site of the parent container shared with the child container,
+// only the inherited singletons that don't depend on the shadowed services are shared.
+func (c *childFactory) sharedCallSite(d *Descriptor, chain *callSiteChain) (CallSite, bool) {
+	if !d.isSingleton() || c.own[d] {
+		return nil, false
+	}
+
+	callSite, err := c.base.GetCallSiteByDescriptor(d, chain)
+	if err != nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dependsOnShadowed(callSite) {
+		return nil, false
+	}
+	c.shared[callSite] = true
+	return callSite, true
+}
+
+// Determines if the singleton call site is shared with the parent container.
+func (c *childFactory) isShared(callSite CallSite) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.shared[callSite]
+}
+
+// Determines if the call site of the base factory depends on any of the shadowed services,
+// the services resolved on demand are checked by their identifiers.
+func (c *childFactory) dependsOnShadowed(callSite CallSite) bool {
+	if result, ok := c.shadowing[callSite]; ok {
+		return result
+	}
+
+	result := false
+	switch cs := callSite.(type) {
+	case *LazyCallSite:
+		result = c.ownIds[cs.Service]
+	case *ProviderCallSite:
+		result = c.ownIds[cs.Service]
+	}
+
+	for _, dep := range callSiteDependencies(callSite) {
+		if result {
+			break
+		}
+		result = c.dependsOnShadowed(dep)
+	}
+
+	c.shadowing[callSite] = result
+	return result
+}
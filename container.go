@@ -1,6 +1,7 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 
@@ -12,11 +13,20 @@ var ContainerType = reflectx.TypeOf[Container]()
 var ContainerImplType = reflectx.TypeOf[container]()
 var ScopeFactoryType = reflectx.TypeOf[ScopeFactory]()
 var IsServiceType = reflectx.TypeOf[IsService]()
+var IsKeyedServiceType = reflectx.TypeOf[IsKeyedService]()
+var ChildFactoryType = reflectx.TypeOf[ChildFactory]()
 
 // Container options.
 type Options struct {
 	ValidateScopes  bool
 	ValidateOnBuild bool
+	// resolve all of the singleton services during Build, otherwise only the ones marked Eager.
+	EagerSingletons bool
+	// the maximum number of the singleton services constructed concurrently during Build,
+	// GOMAXPROCS if it's not positive.
+	WarmUpConcurrency int
+	// called after each of the singleton services is constructed during Build.
+	OnWarmUp func(WarmUpResult)
 }
 
 // Get default container options.
@@ -29,24 +39,74 @@ type container struct {
 	Root              *ContainerEngineScope
 	CallSiteFactory   *CallSiteFactory
 	engine            ContainerEngine
-	realizedServices  *syncx.Map[reflect.Type, ServiceAccessor]
+	realizedServices  *syncx.Map[ServiceIdentifier, ServiceAccessor]
 	disposed          bool
 	callSiteValidator *CallSiteValidator
+	lifecycle         *lifecycle
+	// the container that created the child container, nil if the container is not a child.
+	parent *container
 }
 
 func (c *container) Get(serviceType reflect.Type) (any, error) {
-	return c.GetWithScope(serviceType, c.Root)
+	return c.GetWithScope(newServiceIdentifier(serviceType, nil), c.Root)
+}
+
+func (c *container) GetKeyed(serviceType reflect.Type, key any) (any, error) {
+	return c.GetWithScope(newServiceIdentifier(serviceType, key), c.Root)
 }
 
 func (c *container) CreateScope() Scope {
+	return c.CreateScopeWith(nil)
+}
+
+func (c *container) CreateScopeWith(configure func(ScopeBuilder)) Scope {
 	if c.disposed {
 		panic(fmt.Errorf("%v disposed", reflect.TypeOf(c).Elem()))
 	}
 
-	return newEngineScope(c, false)
+	scope := newEngineScope(c, false)
+	scope.locals = newScopeLocalFactory(c.CallSiteFactory, configure)
+	return scope
 }
 
-func (c *container) GetWithScope(serviceType reflect.Type, scope *ContainerEngineScope) (result any, err error) {
+// Create a child container with the services registered by configure, the services shadow the ones of c.
+// The other services of c are inherited, their dependencies are resolved to the shadowing services,
+// the singletons of c that don't depend on the shadowed services are shared with the child container,
+// while the other singletons live in the child container.
+// The child container is disposed independently of c.
+func (c *container) CreateChild(configure func(ContainerBuilder)) Container {
+	if c.disposed {
+		panic(fmt.Errorf("%v disposed", reflect.TypeOf(c).Elem()))
+	}
+
+	b := &containerBuilder{}
+	validateScopes := c.callSiteValidator != nil
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = validateScopes
+	})
+	if configure != nil {
+		configure(b)
+	}
+	return b.build(c)
+}
+
+// the root scope of the container that owns the call site cached in the root scope.
+func (c *container) rootOf(callSite CallSite) *ContainerEngineScope {
+	for c.parent != nil {
+		if k, ok := callSite.Cache().Key.ServiceKey.(childKey); ok && k.owner == c.CallSiteFactory {
+			break
+		}
+		c = c.parent
+	}
+	return c.Root
+}
+
+// Determines if the singleton call site is shared with the child container by its parent container.
+func (c *container) isSharedSingleton(callSite CallSite) bool {
+	return c.CallSiteFactory.child != nil && c.CallSiteFactory.child.isShared(callSite)
+}
+
+func (c *container) GetWithScope(id ServiceIdentifier, scope *ContainerEngineScope) (result any, err error) {
 	if c.disposed {
 		err = fmt.Errorf("%v disposed", reflect.TypeOf(c).Elem())
 		return
@@ -62,19 +122,19 @@ func (c *container) GetWithScope(serviceType reflect.Type, scope *ContainerEngin
 		}
 	}()
 
-	accessor, ok := c.realizedServices.Load(serviceType)
+	accessor, ok := c.realizedServices.Load(id)
 	if !ok {
-		accessor, err = c.createServiceAccessor(serviceType)
+		accessor, err = c.createServiceAccessor(id)
 		if err != nil {
 			return
 		} else {
-			accessor, _ = c.realizedServices.LoadOrStore(serviceType, accessor)
+			accessor, _ = c.realizedServices.LoadOrStore(id, accessor)
 		}
 
 	}
 
 	if c.callSiteValidator != nil {
-		err := c.callSiteValidator.ValidateResolution(serviceType, scope, c.Root)
+		err := c.callSiteValidator.ValidateResolution(id, scope, c.Root)
 		if err != nil {
 			return nil, err
 		}
@@ -89,14 +149,17 @@ func (c *container) validateService(d *Descriptor) error {
 		return err
 	}
 	if c.callSiteValidator != nil {
-		return c.callSiteValidator.ValidateCallSite(callSite)
+		return c.callSiteValidator.ValidateCallSite(d.Identifier(), callSite)
 	}
 	return nil
 }
 
-func (c *container) Dispose() {
-	c.disposed = true
-	c.Root.Dispose()
+func (c *container) Dispose() error {
+	return c.Root.Dispose()
+}
+
+func (c *container) DisposeContext(ctx context.Context) error {
+	return c.Root.DisposeContext(ctx)
 }
 
 func (c *container) IsDisposed() bool {
@@ -107,14 +170,14 @@ func (c *container) createEngine() ContainerEngine {
 	return newContainerEngine(c)
 }
 
-func (c *container) createServiceAccessor(serviceType reflect.Type) (ServiceAccessor, error) {
-	callSite, err := c.CallSiteFactory.GetCallSite(serviceType, newCallSiteChain())
+func (c *container) createServiceAccessor(id ServiceIdentifier) (ServiceAccessor, error) {
+	callSite, err := c.CallSiteFactory.GetCallSiteByIdentifier(id, newCallSiteChain())
 	if err != nil {
 		return nil, err
 	}
 
 	if c.callSiteValidator != nil {
-		if err := c.callSiteValidator.ValidateCallSite(callSite); err != nil {
+		if err := c.callSiteValidator.ValidateCallSite(id, callSite); err != nil {
 			return nil, err
 		}
 	}
@@ -130,6 +193,6 @@ func (c *container) createServiceAccessor(serviceType reflect.Type) (ServiceAcce
 	return c.engine.RealizeService(callSite)
 }
 
-func (c *container) ReplaceServiceAccessor(callSite CallSite, accessor ServiceAccessor) {
-	c.realizedServices.Store(callSite.ServiceType(), accessor)
+func (c *container) ReplaceServiceAccessor(id ServiceIdentifier, accessor ServiceAccessor) {
+	c.realizedServices.Store(id, accessor)
 }
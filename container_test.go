@@ -1,9 +1,11 @@
 package di
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -177,7 +179,7 @@ func TestContainer_DisposeSingletonWithConstructor(t *testing.T) {
 		return
 	}
 
-	d, _ := c.(Disposable)
+	d, _ := c.(DisposableWithError)
 	d.Dispose()
 	if !obj.Disposed {
 		t.Error("expect disposed")
@@ -200,7 +202,7 @@ func TestContainer_NotDisposeConstant(t *testing.T) {
 		return
 	}
 
-	d, _ := c.(Disposable)
+	d, _ := c.(DisposableWithError)
 	d.Dispose()
 
 	if obj.Disposed {
@@ -496,3 +498,438 @@ func TestInvoke(t *testing.T) {
 		t.Error("assertion failed")
 	}
 }
+
+// Container that only implements Get.
+type getOnlyContainer struct {
+	Container
+}
+
+func TestContainer_KeyedServiceNotSupported(t *testing.T) {
+	b := Builder()
+	AddSingleton[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	c := getOnlyContainer{b.Build()}
+
+	if _, err := TryGetKeyed[*DisposableStruct](c, "primary"); err == nil {
+		t.Error("expect an error if the container doesn't implement KeyedContainer")
+	}
+	if v, err := TryGetKeyed[*DisposableStruct](c, nil); err != nil || v != Get[*DisposableStruct](c) {
+		t.Error("assertion failed")
+	}
+}
+
+func TestContainer_KeyedService(t *testing.T) {
+	b := Builder()
+	AddKeyedSingleton[*DisposableStruct](b, "primary", func() *DisposableStruct { return &DisposableStruct{Value: 1} })
+	AddKeyedSingleton[*DisposableStruct](b, "replica", func() *DisposableStruct { return &DisposableStruct{Value: 2} })
+	AddKeyedScoped[*DisposableStruct](b, "replica", func() *DisposableStruct { return &DisposableStruct{Value: 3} })
+	AddTransient[int](b, func(d *DisposableStruct) int { return d.Value }, ParamKey(0, "primary"))
+
+	c := b.Build()
+
+	if _, err := TryGet[*DisposableStruct](c); err == nil {
+		t.Error("expect an error for the non-keyed service")
+	}
+
+	primary := GetKeyed[*DisposableStruct](c, "primary")
+	if primary.Value != 1 || primary != GetKeyed[*DisposableStruct](c, "primary") {
+		t.Error("assertion failed")
+	}
+
+	if v := Get[int](c); v != 1 {
+		t.Errorf("expected %v actual %v", 1, v)
+	}
+
+	scope1 := Get[ScopeFactory](c).CreateScope()
+	scope2 := Get[ScopeFactory](c).CreateScope()
+	replica1 := GetKeyed[*DisposableStruct](scope1.Container(), "replica")
+	replica2 := GetKeyed[*DisposableStruct](scope2.Container(), "replica")
+	if replica1.Value != 3 || replica1 == replica2 || replica1 != GetKeyed[*DisposableStruct](scope1.Container(), "replica") {
+		t.Error("assertion failed")
+	}
+
+	replicas := GetKeyed[[]*DisposableStruct](scope1.Container(), "replica")
+	if len(replicas) != 2 || replicas[0].Value != 2 || replicas[1] != replica1 {
+		t.Error("assertion failed")
+	}
+
+	isService := Get[IsKeyedService](c)
+	if !isService.IsKeyedService(reflectx.TypeOf[*DisposableStruct](), "primary") ||
+		isService.IsKeyedService(reflectx.TypeOf[*DisposableStruct](), "other") {
+		t.Error("assertion failed")
+	}
+
+	_, err := TryGetKeyed[*DisposableStruct](c, "other")
+	if e, ok := err.(*errorx.ServiceNotFound); !ok || e.ServiceKey != "other" {
+		t.Error("assertion failed")
+	}
+}
+
+func TestContainer_As(t *testing.T) {
+	b := Builder()
+	AddTransient[Iface1](b, func() *E { return &E{} })
+	AddSingleton[*D](b, func() *D { return &D{} }, As[Iface2]())
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} }, AsImplementedInterfaces())
+	AddInstance[Disposable](b, &DisposableStruct{Value: 1})
+
+	c := b.Build()
+
+	d := Get[*D](c)
+	if Get[Iface2](c) != d || Get[[]Iface2](c)[0] != d {
+		t.Error("assertion failed")
+	}
+
+	if !Get[IsService](c).IsService(reflectx.TypeOf[Iface2]()) {
+		t.Error("assertion failed")
+	}
+
+	if _, err := TryGet[Iface1](c); err != nil {
+		t.Error("expect the interface not implemented to be not forwarded")
+	}
+
+	scope := Get[ScopeFactory](c).CreateScope()
+	obj := Get[*DisposableStruct](scope.Container())
+	disposables := Get[[]Disposable](scope.Container())
+	if len(disposables) != 2 || disposables[0] != obj || disposables[1] != Get[Disposable](scope.Container()) {
+		t.Error("assertion failed")
+	}
+
+	scope.Dispose()
+	if !obj.Disposed {
+		t.Error("expect disposed")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect a panic for the type not implemented")
+			}
+		}()
+		AddSingleton[*F](b, func() *F { return &F{} }, As[Iface1]())
+	}()
+}
+
+func TestContainer_As_Registered(t *testing.T) {
+	explicit := &DisposableStruct{Value: 1}
+	b := Builder()
+	AddInstance[Disposable](b, explicit)
+	AddSingleton[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{Value: 2} }, AsImplementedInterfaces())
+	AddSingleton[*D](b, func() *D { return &D{} }, As[Iface1]())
+	Decorate[Iface1](b, func(inner Iface1) Iface1 { return &E{} })
+
+	c := b.Build()
+	if Get[Disposable](c) != explicit || len(Get[[]Disposable](c)) != 2 {
+		t.Error("expect the implicit forwarding not to shadow the service registered explicitly")
+	}
+
+	if _, ok := Get[Iface1](c).(*E); !ok {
+		t.Error("expect the forwarded service type decorated")
+	}
+
+	b = Builder()
+	AddSingleton[*F](b, func() *F { return &F{} }, As[Iface2]())
+	b.Replace(Singleton[*F](func() *F { return &F{} }))
+	if _, err := TryGet[Iface2](b.Build()); err == nil {
+		t.Error("expect the forwarding replaced with the descriptor")
+	}
+
+	b = Builder()
+	AddSingleton[*F](b, func() *F { return &F{} }, As[Iface2]())
+	b.Remove(reflectx.TypeOf[*F]())
+	if _, err := TryGet[Iface2](b.Build()); err == nil {
+		t.Error("expect the forwarding removed with the descriptor")
+	}
+}
+
+func TestContainer_Cleanup(t *testing.T) {
+	released := make([]string, 0)
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() (*DisposableStruct, func(), error) {
+		return &DisposableStruct{}, func() { released = append(released, "scoped") }, nil
+	})
+	AddSingleton[int](b, func() (int, func()) {
+		return 1, func() { released = append(released, "singleton") }
+	})
+	AddTransient[string](b, func() (string, func(), error) {
+		return "", func() { released = append(released, "failed") }, errors.New("failed")
+	})
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	obj := Get[*DisposableStruct](scope.Container())
+	_ = Get[int](scope.Container())
+	if _, err := TryGet[string](scope.Container()); err == nil {
+		t.Error("expect the error of the constructor")
+	}
+
+	scope.Dispose()
+	if !obj.Disposed || len(released) != 1 || released[0] != "scoped" {
+		t.Error("expect the cleanup called with the scope")
+	}
+
+	c.(DisposableWithError).Dispose()
+	if len(released) != 2 || released[1] != "singleton" {
+		t.Error("expect the cleanup called with the root")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect a panic for the invalid constructor")
+			}
+		}()
+		AddTransient[int](b, func() (int, error, func()) { return 0, nil, nil })
+	}()
+}
+
+type closerStruct struct {
+	Err    error
+	Closed bool
+}
+
+func (c *closerStruct) Close() error {
+	c.Closed = true
+	return c.Err
+}
+
+type disposableWithErrorStruct struct {
+	Err error
+}
+
+func (d *disposableWithErrorStruct) Dispose() error {
+	return d.Err
+}
+
+func TestContainer_DisposeWithError(t *testing.T) {
+	b := Builder()
+	AddScoped[*closerStruct](b, func() *closerStruct { return &closerStruct{Err: errors.New("close failed")} })
+	AddScoped[*disposableWithErrorStruct](b, func() *disposableWithErrorStruct {
+		return &disposableWithErrorStruct{Err: errors.New("dispose failed")}
+	})
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	closer := Get[*closerStruct](scope.Container())
+	_ = Get[*disposableWithErrorStruct](scope.Container())
+	obj := Get[*DisposableStruct](scope.Container())
+
+	err := scope.Dispose()
+	if !closer.Closed || !obj.Disposed {
+		t.Error("expect disposed")
+	}
+
+	var aggregate *errorx.AggregateError
+	if !errors.As(err, &aggregate) || len(aggregate.Errors) != 2 {
+		t.Fatal("expect an AggregateError of 2 errors")
+	}
+
+	var disposeErr *errorx.DisposeError
+	if !errors.As(aggregate.Errors[0], &disposeErr) || disposeErr.ServiceType != reflect.TypeOf(&disposableWithErrorStruct{}) {
+		t.Error("expect the errors annotated by the service type in the reverse order")
+	}
+
+	if err := c.(DisposableWithError).Dispose(); err != nil {
+		t.Error("assertion failed")
+	}
+}
+
+type disposableContextStruct struct {
+	release chan struct{}
+}
+
+// the disposal doesn't respect the context, it blocks until released.
+func (d *disposableContextStruct) Dispose(ctx context.Context) error {
+	<-d.release
+	return nil
+}
+
+func TestContainer_DisposeContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	AddScoped[*disposableContextStruct](b, func() *disposableContextStruct {
+		return &disposableContextStruct{release: release}
+	})
+	AddSingleton[*closerStruct](b, func() *closerStruct { return &closerStruct{} })
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	obj := Get[*DisposableStruct](scope.Container())
+	_ = Get[*disposableContextStruct](scope.Container())
+	closer := Get[*closerStruct](c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := scope.DisposeContext(ctx)
+	var aggregate *errorx.AggregateError
+	if !errors.As(err, &aggregate) || len(aggregate.Errors) != 1 {
+		t.Fatal("expect an AggregateError of 1 error")
+	}
+
+	var canceled *errorx.DisposeCanceledError
+	if !errors.As(aggregate.Errors[0], &canceled) ||
+		len(canceled.ServiceTypes) != 2 ||
+		canceled.ServiceTypes[0] != reflect.TypeOf(&disposableContextStruct{}) ||
+		!errors.Is(canceled, context.DeadlineExceeded) {
+		t.Error("expect the services didn't finish reported")
+	}
+	if obj.Disposed {
+		t.Error("expect not disposed after the context is done")
+	}
+
+	root := c.(interface{ DisposeContext(context.Context) error })
+	if err := root.DisposeContext(context.Background()); err != nil || !closer.Closed {
+		t.Error("expect disposed with the root")
+	}
+}
+
+func TestContainer_NestedScope(t *testing.T) {
+	count := 0
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct {
+		count++
+		return &DisposableStruct{Value: count}
+	})
+	AddScoped[*closerStruct](b, func() *closerStruct { return &closerStruct{} }, ScopedTo(ScopeMode_Outermost))
+
+	c := b.Build()
+	request := Get[ScopeFactory](c).CreateScope()
+	obj := Get[*DisposableStruct](request.Container())
+
+	unitOfWork := request.CreateScope()
+	if Get[*DisposableStruct](unitOfWork.Container()) != obj {
+		t.Error("expect the instance of the parent scope shared")
+	}
+
+	closer := Get[*closerStruct](unitOfWork.Container())
+	if Get[*closerStruct](request.Container()) != closer {
+		t.Error("expect the instance stored in the outermost scope")
+	}
+
+	sibling := request.CreateScope()
+	nested := sibling.CreateScope()
+	if Get[*DisposableStruct](nested.Container()) != obj {
+		t.Error("expect the instance of the ancestor scope shared")
+	}
+
+	other := Get[ScopeFactory](c).CreateScope()
+	otherChild := other.CreateScope()
+	childObj := Get[*DisposableStruct](otherChild.Container())
+	if childObj == obj || Get[*DisposableStruct](other.Container()) == childObj {
+		t.Error("expect the instance stored in the nearest scope")
+	}
+
+	otherChild.Dispose()
+	if !childObj.Disposed {
+		t.Error("expect disposed with the nested scope")
+	}
+
+	request.Dispose()
+	if !obj.Disposed || !closer.Closed {
+		t.Error("expect disposed with the parent scope")
+	}
+	if _, err := TryGet[*DisposableStruct](nested.Container()); err == nil {
+		t.Error("expect the nested scopes disposed with the parent scope")
+	}
+}
+
+type tenantBilling struct {
+	Storage *DisposableStruct
+	Closer  *closerStruct
+}
+
+func TestContainer_CreateChild(t *testing.T) {
+	b := Builder()
+	AddSingleton[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{Value: 1} })
+	AddSingleton[*closerStruct](b, func() *closerStruct { return &closerStruct{} })
+
+	c := b.Build()
+	parentStorage := Get[*DisposableStruct](c)
+
+	child := Get[ChildFactory](c).CreateChild(func(cb ContainerBuilder) {
+		AddSingleton[*DisposableStruct](cb, func() *DisposableStruct { return &DisposableStruct{Value: 2} })
+		AddSingleton[*tenantBilling](cb, func(s *DisposableStruct, c *closerStruct) *tenantBilling {
+			return &tenantBilling{Storage: s, Closer: c}
+		})
+	})
+
+	storage := Get[*DisposableStruct](child)
+	if storage.Value != 2 || Get[*DisposableStruct](c) != parentStorage {
+		t.Error("expect the service of the child container shadow the parent's")
+	}
+	if s := Get[[]*DisposableStruct](child); len(s) != 1 || s[0] != storage {
+		t.Error("expect the slice of the child container shadow the parent's")
+	}
+
+	closer := Get[*closerStruct](child)
+	if closer != Get[*closerStruct](c) {
+		t.Error("expect the singleton of the parent container shared")
+	}
+	if billing := Get[*tenantBilling](child); billing.Storage != storage || billing.Closer != closer {
+		t.Error("expect the child service depend on the shadowed and the shared services")
+	}
+	if !Get[IsService](child).IsService(reflectx.TypeOf[*closerStruct]()) {
+		t.Error("expect the services of the parent container available")
+	}
+
+	scope := Get[ScopeFactory](child).CreateScope()
+	if Get[*DisposableStruct](scope.Container()) != storage {
+		t.Error("expect the scope created by the child container")
+	}
+
+	child.(DisposableWithError).Dispose()
+	if !storage.Disposed || parentStorage.Disposed || closer.Closed {
+		t.Error("expect only the singletons of the child container disposed")
+	}
+	if _, err := TryGet[*closerStruct](c); err != nil {
+		t.Error("expect the parent container not disposed")
+	}
+
+	c.(DisposableWithError).Dispose()
+	if !parentStorage.Disposed || !closer.Closed {
+		t.Error("expect the singletons of the parent container disposed")
+	}
+}
+
+type tenantReport struct {
+	Storage *DisposableStruct
+}
+
+func TestContainer_CreateChild_Inherited(t *testing.T) {
+	b := Builder()
+	AddSingleton[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{Value: 1} })
+	AddSingleton[*closerStruct](b, func() *closerStruct { return &closerStruct{} })
+	AddTransient[*tenantBilling](b, func(s *DisposableStruct, c *closerStruct) *tenantBilling {
+		return &tenantBilling{Storage: s, Closer: c}
+	})
+	AddSingleton[*tenantReport](b, func(s *DisposableStruct) *tenantReport { return &tenantReport{Storage: s} })
+
+	c := b.Build()
+	parentReport := Get[*tenantReport](c)
+
+	child := Get[ChildFactory](c).CreateChild(func(cb ContainerBuilder) {
+		AddSingleton[*DisposableStruct](cb, func() *DisposableStruct { return &DisposableStruct{Value: 2} })
+	})
+
+	storage := Get[*DisposableStruct](child)
+	billing := Get[*tenantBilling](child)
+	if billing.Storage != storage || billing.Closer != Get[*closerStruct](c) {
+		t.Error("expect the dependencies of the inherited service resolved to the shadowing services")
+	}
+	if Get[*tenantBilling](c).Storage == storage {
+		t.Error("expect the parent container not affected by the child")
+	}
+
+	report := Get[*tenantReport](child)
+	if report == parentReport || report.Storage != storage || Get[*tenantReport](child) != report {
+		t.Error("expect the singleton depending on the shadowed service rebuilt in the child container")
+	}
+
+	scope := Get[ScopeFactory](child).CreateScope()
+	if Get[*tenantBilling](scope.Container()).Storage != storage {
+		t.Error("expect the scopes of the child container resolve the shadowing services")
+	}
+}
@@ -0,0 +1,43 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/dozm/di/reflectx"
+)
+
+func newDecoratorInfo(serviceType reflect.Type, decorator any) (*ConstructorInfo, error) {
+	ci, err := newConstructorInfo(decorator)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ci.In) == 0 || ci.In[0] != serviceType {
+		return nil, fmt.Errorf("the first input parameter of the decorator of the service '%v' must be a '%v'", serviceType, serviceType)
+	}
+
+	if err = checkConstructor(ci, serviceType); err != nil {
+		return nil, err
+	}
+
+	return ci, nil
+}
+
+// Decorate the service T registered in the ContainerBuilder.
+// T is the service type,
+// cb is the ContainerBuilder,
+// decorator is a function like func(inner T, deps...) T or func(inner T, deps...) (T, error),
+// the deps are resolved from the Container.
+// The decorator is applied to each of the descriptors of T registered without a service key, and can be applied multiple times.
+// The instance created by the decorator is disposed with the scope, the decorated instance is disposed as if
+// it wasn't decorated, e.g. an instance registered by Instance is never disposed by the Container.
+func Decorate[T any](cb ContainerBuilder, decorator any) {
+	cb.Decorate(reflectx.TypeOf[T](), decorator)
+}
+
+// Decorate the keyed service T registered in the ContainerBuilder with the service key key,
+// the decorator is the same as the one of Decorate.
+func DecorateKeyed[T any](cb ContainerBuilder, key any, decorator any) {
+	cb.DecorateKeyed(reflectx.TypeOf[T](), key, decorator)
+}
@@ -0,0 +1,160 @@
+package di
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dozm/di/errorx"
+)
+
+type greeter interface{ Greet() string }
+
+type greeterFunc struct{ f func() string }
+
+func (g *greeterFunc) Greet() string { return g.f() }
+
+func TestDecorate_Layers(t *testing.T) {
+	count := 0
+	b := Builder()
+	AddSingleton[greeter](b, func() greeter {
+		count++
+		return &greeterFunc{func() string { return "hello" }}
+	})
+	AddInstance[string](b, "!")
+	Decorate[greeter](b, func(inner greeter, suffix string) greeter {
+		return &greeterFunc{func() string { return inner.Greet() + suffix }}
+	})
+	Decorate[greeter](b, func(inner greeter) (greeter, error) {
+		return &greeterFunc{func() string { return "[" + inner.Greet() + "]" }}, nil
+	})
+
+	c := b.Build()
+
+	g := Get[greeter](c)
+	if s := g.Greet(); s != "[hello!]" {
+		t.Errorf("expected %v actual %v", "[hello!]", s)
+	}
+
+	scope := Get[ScopeFactory](c).CreateScope()
+	if Get[greeter](scope.Container()) != g || Get[[]greeter](c)[0] != g || count != 1 {
+		t.Error("expect the decorated singleton to be cached in the same slot")
+	}
+}
+
+func TestDecorate_Slice(t *testing.T) {
+	b := Builder()
+	AddTransient[int](b, func() int { return 1 })
+	AddScoped[int](b, func() int { return 2 })
+	Decorate[int](b, func(inner int) int { return inner * 10 })
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+
+	if s := fmt.Sprint(Get[[]int](scope.Container())); s != "[10 20]" {
+		t.Errorf("expected %v actual %v", "[10 20]", s)
+	}
+
+	if v := Get[int](scope.Container()); v != 20 {
+		t.Errorf("expected %v actual %v", 20, v)
+	}
+}
+
+func TestDecorate_Dispose(t *testing.T) {
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{Value: 1} })
+	AddTransient[Disposable](b, func() *DisposableStruct { return &DisposableStruct{Value: 1} })
+	Decorate[*DisposableStruct](b, func(inner *DisposableStruct) *DisposableStruct {
+		return &DisposableStruct{Value: inner.Value + 1}
+	})
+	Decorate[Disposable](b, func(inner Disposable) Disposable { return inner })
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+
+	obj := Get[*DisposableStruct](scope.Container())
+	d := Get[Disposable](scope.Container())
+	if obj.Value != 2 {
+		t.Error("assertion failed")
+	}
+
+	scope.Dispose()
+	if !obj.Disposed || !d.(*DisposableStruct).Disposed {
+		t.Error("expect disposed")
+	}
+}
+
+func TestDecorate_NotRegistered(t *testing.T) {
+	b := Builder()
+
+	defer func() {
+		if _, ok := recover().(*errorx.ServiceNotFound); !ok {
+			t.Error("assertion failed")
+		}
+	}()
+
+	Decorate[int](b, func(inner int) int { return inner })
+}
+
+type disposeCounter struct{ Count int }
+
+func (d *disposeCounter) Dispose() { d.Count++ }
+
+func TestDecorate_DisposeOnce(t *testing.T) {
+	b := Builder()
+	AddScoped[*disposeCounter](b, func() *disposeCounter { return &disposeCounter{} })
+	Decorate[*disposeCounter](b, func(inner *disposeCounter) *disposeCounter { return inner })
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	d := Get[*disposeCounter](scope.Container())
+
+	scope.Dispose()
+	if d.Count != 1 {
+		t.Errorf("expected %v actual %v", 1, d.Count)
+	}
+}
+
+func TestDecorate_Instance(t *testing.T) {
+	instance := &disposeCounter{}
+	b := Builder()
+	AddInstance[*disposeCounter](b, instance)
+	Decorate[*disposeCounter](b, func(inner *disposeCounter) *disposeCounter { return inner })
+	AddInstance[Disposable](b, instance)
+	Decorate[Disposable](b, func(inner Disposable) Disposable { return &disposeCounter{} })
+
+	c := b.Build()
+	if Get[*disposeCounter](c) != instance {
+		t.Error("assertion failed")
+	}
+	decorated := Get[Disposable](c).(*disposeCounter)
+
+	c.(DisposableWithError).Dispose()
+	if instance.Count != 0 {
+		t.Error("expect the instance owned by the user not disposed")
+	}
+	if decorated.Count != 1 {
+		t.Error("expect the instance created by the decorator disposed")
+	}
+}
+
+func TestDecorateKeyed(t *testing.T) {
+	b := Builder()
+	AddKeyedSingleton[string](b, "a", func() string { return "a" })
+	AddKeyedSingleton[string](b, "b", func() string { return "b" })
+	DecorateKeyed[string](b, "a", func(inner string) string { return inner + "!" })
+
+	c := b.Build()
+	if s := GetKeyed[string](c, "a"); s != "a!" {
+		t.Errorf("expected %v actual %v", "a!", s)
+	}
+	if s := GetKeyed[string](c, "b"); s != "b" {
+		t.Errorf("expected %v actual %v", "b", s)
+	}
+
+	defer func() {
+		if _, ok := recover().(*errorx.ServiceNotFound); !ok {
+			t.Error("assertion failed")
+		}
+	}()
+	DecorateKeyed[string](b, "c", func(inner string) string { return inner })
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 
+	"github.com/dozm/di/errorx"
 	"github.com/dozm/di/reflectx"
 )
 
@@ -13,10 +14,27 @@ const (
 	Lifetime_Singleton Lifetime = iota
 	Lifetime_Scoped
 	Lifetime_Transient
+	// the instances are managed by the LifetimeManager of the descriptor.
+	Lifetime_Custom
+	// the instances are rented from a pool and returned to it when the scope that rented them is disposed.
+	Lifetime_Pooled
+)
+
+// Determines which of the nested scopes stores the instance of a scoped service.
+type ScopeMode byte
+
+const (
+	// the instance is stored in the scope that resolves it, unless any of its parent scopes has resolved it.
+	ScopeMode_Nearest ScopeMode = iota
+	// the instance is stored in the outermost scope of the nested scopes.
+	ScopeMode_Outermost
 )
 
 type Factory func(Container) any
 
+// Factory that returns an error if it fails to create the service.
+type FactoryFunc func(Container) (any, error)
+
 type ConstructorInfo struct {
 	FuncType  reflect.Type
 	FuncValue reflect.Value
@@ -24,44 +42,201 @@ type ConstructorInfo struct {
 	In []reflect.Type
 	// output parameter types
 	Out []reflect.Type
+	// service keys of the input parameters, a nil key means the parameter is not keyed.
+	InKeys []any
+	// parameter objects of the input parameters, nil if the parameter doesn't embed In.
+	InObjects []*StructInfo
+	// calls the constructor without reflection, nil if the constructor is called by reflection.
+	invoke func(args []any) (any, error)
 }
 
 func (c *ConstructorInfo) Call(params []reflect.Value) []reflect.Value {
 	return c.FuncValue.Call(params)
 }
 
-func newConstructorInfo(ctor any) *ConstructorInfo {
+func newConstructorInfo(ctor any) (*ConstructorInfo, error) {
 	ft := reflect.TypeOf(ctor)
-	return &ConstructorInfo{
+	if ft == nil || ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("the constructor '%v' is not a function", ft)
+	}
+
+	ci := &ConstructorInfo{
 		FuncValue: reflect.ValueOf(ctor),
 		FuncType:  ft,
 		In:        reflectx.GetInParameters(ft),
 		Out:       reflectx.GetOutParameters(ft),
 	}
 
+	for i, t := range ci.In {
+		if !isParamObject(t) {
+			continue
+		}
+
+		info, err := newParamObjectInfo(t)
+		if err != nil {
+			return nil, err
+		}
+		if ci.InObjects == nil {
+			ci.InObjects = make([]*StructInfo, len(ci.In))
+		}
+		ci.InObjects[i] = info
+	}
+
+	return ci, nil
+}
+
+// parameter object of the input parameter at index i.
+func (c *ConstructorInfo) InObject(i int) *StructInfo {
+	if i < len(c.InObjects) {
+		return c.InObjects[i]
+	}
+	return nil
+}
+
+// service key of the input parameter at index i.
+func (c *ConstructorInfo) InKey(i int) any {
+	if i < len(c.InKeys) {
+		return c.InKeys[i]
+	}
+	return nil
+}
+
+// Identifies a service by its type and an optional key.
+type ServiceIdentifier struct {
+	ServiceType reflect.Type
+	// nil for a non-keyed service.
+	ServiceKey any
+}
+
+func (id ServiceIdentifier) String() string {
+	if id.ServiceKey == nil {
+		return fmt.Sprint(id.ServiceType)
+	}
+	return fmt.Sprintf("%v (key: %v)", id.ServiceType, id.ServiceKey)
+}
+
+func newServiceIdentifier(serviceType reflect.Type, serviceKey any) ServiceIdentifier {
+	return ServiceIdentifier{ServiceType: serviceType, ServiceKey: serviceKey}
 }
 
 // service descriptor
 type Descriptor struct {
 	ServiceType reflect.Type
+	ServiceKey  any // nil for a non-keyed service
 	Lifetime    Lifetime
 	Ctor        *ConstructorInfo
 	Instance    any
 	Factory     func(Container) any
+	// the factory that returns an error, it's set instead of Factory.
+	FactoryFunc FactoryFunc
+	Struct      *StructInfo
+	// the function that the factory is created from, the implementations of the factories are compared by it.
+	factoryImpl any
+	// the descriptor that a forwarding descriptor resolves to.
+	Forward *Descriptor
+	// additional service types resolved to the same instance.
+	As []reflect.Type
+	// forward the interface service types registered in the container that the implementation implements.
+	AsImplementedInterfaces bool
+	// decorators applied in order, the last one is the outermost.
+	Decorators []*ConstructorInfo
+	// name of the module that registered the descriptor, empty if it's not registered by a module.
+	Module string
+	// indices of the constructor parameters supplied by the arguments of the assisted factory,
+	// nil if the descriptor is not an assisted factory.
+	Assisted []int
+	// hooks called in order after the instance is created, they can replace the instance.
+	Activating []ActivatingHook
+	// hooks called in order after the activating hooks.
+	Activated []ActivatedHook
+	// hooks called in order before the instance is disposed with its owning scope.
+	Release []ReleaseHook
+	// the singleton is resolved during Build.
+	Eager bool
+	// the scope that stores the instance of the scoped service in the nested scopes.
+	ScopeMode ScopeMode
+	// the service is provided by the scopes created with CreateScopeWith.
+	ScopeLocal bool
+	// the manager of the instances, nil if the lifetime is a built-in one.
+	LifetimeManager LifetimeManager
+}
+
+func (d *Descriptor) Identifier() ServiceIdentifier {
+	return newServiceIdentifier(d.ServiceType, d.ServiceKey)
 }
 
 func (d *Descriptor) String() string {
 	s := fmt.Sprintf("ServiceType: %v Lifetime: %v ", d.ServiceType, d.Lifetime)
+	if d.ServiceKey != nil {
+		s += fmt.Sprintf("ServiceKey: %v ", d.ServiceKey)
+	}
 
-	if d.Ctor != nil {
+	if d.Assisted != nil {
+		s += fmt.Sprintf("Assisted: %v", d.Ctor.FuncType)
+	} else if d.Ctor != nil {
 		s += fmt.Sprintf("Constructor: %v", d.Ctor.FuncType)
+	} else if d.Struct != nil {
+		s += fmt.Sprintf("Struct: %v", d.Struct.Type)
+	} else if d.Factory != nil || d.FactoryFunc != nil {
+		s += fmt.Sprintf("Factory: %v", reflect.TypeOf(d.factoryImpl))
+	} else if d.Forward != nil {
+		s += fmt.Sprintf("Forward: %v", d.Forward.ServiceType)
+	} else if d.ScopeLocal {
+		s += "ScopeLocal"
 	} else {
 		s += fmt.Sprintf("Instance: %v", d.Instance)
 	}
 
+	if d.Module != "" {
+		s += fmt.Sprintf(" Module: %v", d.Module)
+	}
+
 	return s
 }
 
+// Determines if the instance of the service is shared by the root scope,
+// including the services whose LifetimeManager is validated as singleton, except the pooled ones.
+func (d *Descriptor) isSingleton() bool {
+	return d.Lifetime == Lifetime_Singleton ||
+		d.Lifetime == Lifetime_Custom && d.LifetimeManager.Lifetime() == Lifetime_Singleton
+}
+
+// The type of the instances that the descriptor provides.
+func (d *Descriptor) ImplementationType() reflect.Type {
+	switch {
+	case d.Instance != nil:
+		return reflect.TypeOf(d.Instance)
+	case d.Assisted != nil:
+		return d.ServiceType
+	case d.Ctor != nil:
+		return d.Ctor.Out[0]
+	case d.Forward != nil:
+		return d.Forward.ImplementationType()
+	default:
+		return d.ServiceType
+	}
+}
+
+// Determines if the descriptors provide the same implementation,
+// the constructors and factories are compared by the function code, the instances by the value,
+// and the structs by the type.
+// The closures created by the same function literal share the code, so they're the same implementation
+// even if they capture different variables.
+func sameImplementation(a, b *Descriptor) bool {
+	switch {
+	case a.Ctor != nil && b.Ctor != nil:
+		return a.Ctor.FuncValue.Pointer() == b.Ctor.FuncValue.Pointer()
+	case a.factoryImpl != nil && b.factoryImpl != nil:
+		return reflect.ValueOf(a.factoryImpl).Pointer() == reflect.ValueOf(b.factoryImpl).Pointer()
+	case a.Instance != nil && b.Instance != nil:
+		return sameInstance(a.Instance, b.Instance)
+	case a.Struct != nil && b.Struct != nil:
+		return a.ImplementationType() == b.ImplementationType()
+	default:
+		return false
+	}
+}
+
 func NewInstanceDescriptor(serviceType reflect.Type, instance any) *Descriptor {
 	if err := instanceAssignable(instance, serviceType); err != nil {
 		panic(err)
@@ -75,8 +250,10 @@ func NewInstanceDescriptor(serviceType reflect.Type, instance any) *Descriptor {
 }
 
 func NewConstructorDescriptor(serviceType reflect.Type, lifetime Lifetime, ctor any) *Descriptor {
-	ci := newConstructorInfo(ctor)
-	err := checkConstructor(ci, serviceType)
+	ci, err := newConstructorInfo(ctor)
+	if err == nil {
+		err = checkConstructor(ci, serviceType)
+	}
 
 	if err != nil {
 		panic(err)
@@ -89,6 +266,24 @@ func NewConstructorDescriptor(serviceType reflect.Type, lifetime Lifetime, ctor
 	}
 }
 
+func NewStructDescriptor(serviceType reflect.Type, lifetime Lifetime) *Descriptor {
+	info, err := newStructInfo(serviceType)
+	if err != nil {
+		panic(err)
+	}
+
+	return &Descriptor{
+		ServiceType: serviceType,
+		Lifetime:    lifetime,
+		Struct:      info,
+	}
+}
+
+// The type of the cleanup function returned by a constructor.
+var CleanupType = reflectx.TypeOf[func()]()
+
+// the constructor returns (T), (T, error), (T, func()) or (T, func(), error),
+// the func() is a cleanup function called when the owning scope is disposed.
 func checkConstructor(ctor *ConstructorInfo, serviceType reflect.Type) (err error) {
 	if ctor.FuncType.Kind() != reflect.Func {
 		return fmt.Errorf("the constructor of the service '%v' is not a function", serviceType)
@@ -96,15 +291,27 @@ func checkConstructor(ctor *ConstructorInfo, serviceType reflect.Type) (err erro
 
 	out := ctor.Out
 	numOut := len(out)
-	if (numOut == 0 || numOut > 2) ||
+	if (numOut == 0 || numOut > 3) ||
 		!out[0].AssignableTo(serviceType) ||
-		(numOut == 2 && !reflectx.IsErrorType(out[1])) {
-		return fmt.Errorf("the constructor must returns a '%v' and an optional error", serviceType)
+		(numOut == 2 && !reflectx.IsErrorType(out[1]) && out[1] != CleanupType) ||
+		(numOut == 3 && (out[1] != CleanupType || !reflectx.IsErrorType(out[2]))) {
+		return fmt.Errorf("the constructor must returns a '%v', an optional cleanup function and an optional error", serviceType)
 	}
 
 	return
 }
 
+// Determines if the constructor returns an error as the last output parameter.
+func (c *ConstructorInfo) ReturnsError() bool {
+	n := len(c.Out)
+	return n > 1 && reflectx.IsErrorType(c.Out[n-1])
+}
+
+// Determines if the constructor returns a cleanup function.
+func (c *ConstructorInfo) ReturnsCleanup() bool {
+	return len(c.Out) > 1 && c.Out[1] == CleanupType
+}
+
 func instanceAssignable(instance any, to reflect.Type) (err error) {
 	if t := reflect.TypeOf(instance); !t.AssignableTo(to) {
 		err = fmt.Errorf("the instance of type '%v' can not assignable to type '%v'", t, to)
@@ -112,10 +319,191 @@ func instanceAssignable(instance any, to reflect.Type) (err error) {
 	return
 }
 
+func checkServiceKey(key any) error {
+	if key == nil {
+		return errorx.NewArgumentNilError("key")
+	}
+	if t := reflect.TypeOf(key); !t.Comparable() {
+		return errorx.NewArgumentError(fmt.Sprintf("the service key of type '%v' is not comparable", t))
+	}
+	return nil
+}
+
+func NewKeyedInstanceDescriptor(serviceType reflect.Type, key any, instance any) *Descriptor {
+	if err := checkServiceKey(key); err != nil {
+		panic(err)
+	}
+
+	d := NewInstanceDescriptor(serviceType, instance)
+	d.ServiceKey = key
+	return d
+}
+
+func NewKeyedConstructorDescriptor(serviceType reflect.Type, key any, lifetime Lifetime, ctor any) *Descriptor {
+	if err := checkServiceKey(key); err != nil {
+		panic(err)
+	}
+
+	d := NewConstructorDescriptor(serviceType, lifetime, ctor)
+	d.ServiceKey = key
+	return d
+}
+
 func NewFactoryDescriptor(serviceType reflect.Type, lifetime Lifetime, factory Factory) *Descriptor {
 	return &Descriptor{
 		ServiceType: serviceType,
 		Lifetime:    lifetime,
 		Factory:     factory,
+		factoryImpl: factory,
+	}
+}
+
+func NewFactoryFuncDescriptor(serviceType reflect.Type, lifetime Lifetime, factory FactoryFunc) *Descriptor {
+	return &Descriptor{
+		ServiceType: serviceType,
+		Lifetime:    lifetime,
+		FactoryFunc: factory,
+		factoryImpl: factory,
+	}
+}
+
+// Option to configure a descriptor
+type DescriptorOption func(*Descriptor)
+
+func (d *Descriptor) apply(opts []DescriptorOption) *Descriptor {
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Resolve the input parameter at index of the constructor as the keyed service with the key.
+func ParamKey(index int, key any) DescriptorOption {
+	return func(d *Descriptor) {
+		if d.Ctor == nil {
+			panic(fmt.Errorf("the service '%v' is not registered with a constructor", d.ServiceType))
+		}
+		if index < 0 || index >= len(d.Ctor.In) {
+			panic(errorx.NewArgumentError(fmt.Sprintf("the constructor of the service '%v' has no parameter at index %v", d.ServiceType, index)))
+		}
+		if d.Ctor.InObject(index) != nil {
+			panic(errorx.NewArgumentError(fmt.Sprintf("the parameter at index %v of the constructor of the service '%v' is a parameter object", index, d.ServiceType)))
+		}
+		if key != nil {
+			if err := checkServiceKey(key); err != nil {
+				panic(err)
+			}
+		}
+
+		if d.Ctor.InKeys == nil {
+			d.Ctor.InKeys = make([]any, len(d.Ctor.In))
+		}
+		d.Ctor.InKeys[index] = key
+	}
+}
+
+// Resolve the service type T to the same instance as the registered service.
+func As[T any]() DescriptorOption {
+	return func(d *Descriptor) {
+		t := reflectx.TypeOf[T]()
+		if impl := d.ImplementationType(); !impl.AssignableTo(t) {
+			panic(fmt.Errorf("the implementation '%v' of the service '%v' can not assignable to type '%v'", impl, d.ServiceType, t))
+		}
+		d.As = append(d.As, t)
+	}
+}
+
+// Resolve each of the interface service types registered in the container
+// that the implementation of the service implements to the same instance as the registered service.
+// The forwarded services are resolved in the slices of the interface types,
+// but never shadow the services registered explicitly when an interface type is resolved individually.
+func AsImplementedInterfaces() DescriptorOption {
+	return func(d *Descriptor) {
+		d.AsImplementedInterfaces = true
+	}
+}
+
+// Resolve the singleton service during Build.
+func Eager() DescriptorOption {
+	return func(d *Descriptor) {
+		if !d.isSingleton() {
+			panic(fmt.Errorf("the service '%v' to be resolved eagerly is not a singleton", d.ServiceType))
+		}
+		d.Eager = true
+	}
+}
+
+// Store the instance of the scoped service in the scope determined by the mode when the scopes are nested.
+func ScopedTo(mode ScopeMode) DescriptorOption {
+	return func(d *Descriptor) {
+		if d.Lifetime != Lifetime_Scoped {
+			panic(fmt.Errorf("the service '%v' is not scoped", d.ServiceType))
+		}
+		d.ScopeMode = mode
+	}
+}
+
+func newForwardDescriptor(serviceType reflect.Type, target *Descriptor) *Descriptor {
+	return &Descriptor{
+		ServiceType: serviceType,
+		ServiceKey:  target.ServiceKey,
+		Lifetime:    target.Lifetime,
+		Forward:     target,
+		Module:      target.Module,
+		ScopeMode:   target.ScopeMode,
+
+		LifetimeManager: target.LifetimeManager,
+	}
+}
+
+// The descriptor followed by the forwarding descriptors of the service types added by As,
+// they're expanded when the descriptor is added, so they can be decorated, replaced and removed as registered.
+func withForwards(d *Descriptor) []*Descriptor {
+	result := []*Descriptor{d}
+	forwarded := map[reflect.Type]bool{d.ServiceType: true}
+	for _, t := range d.As {
+		if !forwarded[t] {
+			forwarded[t] = true
+			result = append(result, newForwardDescriptor(t, d))
+		}
+	}
+	return result
+}
+
+// Expand the forwarding descriptors of AsImplementedInterfaces.
+// They precede the registered descriptors, so they're resolved in the slices of the services
+// but never shadow the descriptors registered explicitly.
+func expandForwards(descriptors []*Descriptor) []*Descriptor {
+	interfaces := make([]reflect.Type, 0)
+	seen := make(map[reflect.Type]bool)
+	for _, d := range descriptors {
+		if t := d.ServiceType; t.Kind() == reflect.Interface && !seen[t] {
+			seen[t] = true
+			interfaces = append(interfaces, t)
+		}
+	}
+
+	implicit := make([]*Descriptor, 0)
+	for _, d := range descriptors {
+		if !d.AsImplementedInterfaces {
+			continue
+		}
+
+		forwarded := map[reflect.Type]bool{d.ServiceType: true}
+		for _, t := range d.As {
+			forwarded[t] = true
+		}
+
+		impl := d.ImplementationType()
+		for _, t := range interfaces {
+			if !forwarded[t] && impl.Implements(t) {
+				implicit = append(implicit, newForwardDescriptor(t, d))
+			}
+		}
+	}
+
+	if len(implicit) == 0 {
+		return descriptors
 	}
+	return append(implicit, descriptors...)
 }
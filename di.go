@@ -1,7 +1,9 @@
 package di
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
 
 	"github.com/dozm/di/errorx"
@@ -14,11 +16,33 @@ type Container interface {
 
 type Scope interface {
 	Container() Container
-	Dispose()
+	// Create a scope nested in the scope, the nested scope shares the scoped services resolved by its parent scopes.
+	CreateScope() Scope
+	// Create a nested scope with the services registered by configure.
+	CreateScopeWith(configure func(ScopeBuilder)) Scope
+	// Dispose the services captured by the scope,
+	// it returns an AggregateError if any of the services failed to be disposed.
+	Dispose() error
+	// Dispose the services captured by the scope with the context,
+	// it stops waiting when the context is done and reports the services that didn't finish.
+	DisposeContext(ctx context.Context) error
 }
 
 type ScopeFactory interface {
 	CreateScope() Scope
+	// Create a scope with the services registered by configure,
+	// the services are only visible inside the scope and its nested scopes.
+	CreateScopeWith(configure func(ScopeBuilder)) Scope
+}
+
+// Factory of the child containers, the services registered in a child container shadow the ones of its parent,
+// the other services are resolved from the parent.
+//
+//	tenant := di.Get[di.ChildFactory](c).CreateChild(func(cb di.ContainerBuilder) {
+//		di.AddSingleton[Storage](cb, newTenantStorage)
+//	})
+type ChildFactory interface {
+	CreateChild(configure func(ContainerBuilder)) Container
 }
 
 // Optional service used to determine if the specified type is available from the Container.
@@ -26,10 +50,33 @@ type IsService interface {
 	IsService(serviceType reflect.Type) bool
 }
 
+// Optional service used to determine if the specified keyed service is available from the Container.
+type IsKeyedService interface {
+	IsKeyedService(serviceType reflect.Type, key any) bool
+}
+
+// Optional interface of the Container that resolves the keyed services.
+type KeyedContainer interface {
+	// Get the keyed service of the type and the key.
+	GetKeyed(serviceType reflect.Type, key any) (any, error)
+}
+
 type Disposable interface {
 	Dispose()
 }
 
+// Disposable that reports the failure of disposal,
+// the services implement io.Closer are disposed as well.
+type DisposableWithError interface {
+	Dispose() error
+}
+
+// Disposable that is disposed with the context of the disposal, it should return
+// as soon as possible when the context is done.
+type DisposableContext interface {
+	Dispose(ctx context.Context) error
+}
+
 // Get service of the type T from the container c
 func Get[T any](c Container) T {
 	result, err := TryGet[T](c)
@@ -55,6 +102,44 @@ func TryGet[T any](c Container) (result T, err error) {
 	return
 }
 
+// Get keyed service of the type T and the key from the container c
+func GetKeyed[T any](c Container, key any) T {
+	result, err := TryGetKeyed[T](c, key)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TryGetKeyed[T any](c Container, key any) (result T, err error) {
+	t := reflectx.TypeOf[T]()
+	v, err := getKeyed(c, t, key)
+	if err != nil {
+		return
+	}
+
+	result, ok := v.(T)
+	if !ok {
+		err = &errorx.TypeIncompatibilityError{To: t, From: reflect.TypeOf(v)}
+		return
+	}
+
+	return
+}
+
+// get the keyed service from the container c, which must implement KeyedContainer unless the key is nil.
+func getKeyed(c Container, serviceType reflect.Type, key any) (any, error) {
+	if key == nil {
+		return c.Get(serviceType)
+	}
+
+	kc, ok := c.(KeyedContainer)
+	if !ok {
+		return nil, fmt.Errorf("the container '%T' doesn't implement KeyedContainer", c)
+	}
+	return kc.GetKeyed(serviceType, key)
+}
+
 // Invoke the function fn.
 // the input paramenters of the fn function will be resolved from the Container c.
 func Invoke(c Container, fn any) (fnReturn []any, err error) {
@@ -68,6 +153,21 @@ func Invoke(c Container, fn any) (fnReturn []any, err error) {
 
 	inputs := make([]reflect.Value, len(inputTypes))
 	for i, t := range inputTypes {
+		if isParamObject(t) {
+			info, e := newParamObjectInfo(t)
+			if e != nil {
+				err = e
+				return
+			}
+			fieldValues, e := resolveFields(c, info)
+			if e != nil {
+				err = e
+				return
+			}
+			inputs[i] = reflect.ValueOf(info.New(fieldValues))
+			continue
+		}
+
 		v, e := c.Get(t)
 		if e != nil {
 			err = e
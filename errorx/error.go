@@ -48,9 +48,13 @@ func (e *FuncSignatureError) Error() string {
 
 type ServiceNotFound struct {
 	ServiceType reflect.Type
+	ServiceKey  any
 }
 
 func (e *ServiceNotFound) Error() string {
+	if e.ServiceKey != nil {
+		return fmt.Sprintf("ServiceNotFound '%v' (key: %v)", e.ServiceType, e.ServiceKey)
+	}
 	return fmt.Sprintf("ServiceNotFound '%v'", e.ServiceType)
 }
 
@@ -87,6 +91,46 @@ func (e *ScopedServiceFromRootError) Error() string {
 	return fmt.Sprintf("ScopedServiceFromRootError: %v", e.Message)
 }
 
+type ModuleError struct {
+	Module string
+	Err    error
+}
+
+func (e *ModuleError) Error() string {
+	return fmt.Sprintf("ModuleError: module '%v': %v", e.Module, e.Err)
+}
+
+func (e *ModuleError) Unwrap() error {
+	return e.Err
+}
+
+type DisposeError struct {
+	ServiceType reflect.Type
+	Err         error
+}
+
+func (e *DisposeError) Error() string {
+	return fmt.Sprintf("DisposeError: failed to dispose the service '%v': %v", e.ServiceType, e.Err)
+}
+
+func (e *DisposeError) Unwrap() error {
+	return e.Err
+}
+
+type DisposeCanceledError struct {
+	// the services that didn't finish disposal, in the order of disposal.
+	ServiceTypes []reflect.Type
+	Err          error
+}
+
+func (e *DisposeCanceledError) Error() string {
+	return fmt.Sprintf("DisposeCanceledError: the services %v didn't finish disposal: %v", e.ServiceTypes, e.Err)
+}
+
+func (e *DisposeCanceledError) Unwrap() error {
+	return e.Err
+}
+
 type AggregateError struct {
 	Errors []error
 }
@@ -95,6 +139,14 @@ func (e *AggregateError) Add(err error) {
 	e.Errors = append(e.Errors, err)
 }
 
+// the AggregateError if it has any error, otherwise nil.
+func (e *AggregateError) OrNil() error {
+	if len(e.Errors) > 0 {
+		return e
+	}
+	return nil
+}
+
 func (e *AggregateError) Error() string {
 	var b strings.Builder
 	b.WriteString("AggregateError: \n")
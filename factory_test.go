@@ -1,10 +1,13 @@
 package di
 
 import (
+	"errors"
 	"io"
 	"strings"
 	"sync/atomic"
 	"testing"
+
+	"github.com/dozm/di/reflectx"
 )
 
 type readWriter struct {
@@ -99,3 +102,47 @@ func TestFactory_Scoped(t *testing.T) {
 		}
 	}
 }
+
+func TestFactory_Error(t *testing.T) {
+	b := Builder()
+	b.Add(NewFactoryFuncDescriptor(reflectx.TypeOf[io.Reader](), Lifetime_Transient, func(c Container) (any, error) {
+		return nil, errors.New("failed")
+	}))
+	b.Add(NewFactoryFuncDescriptor(reflectx.TypeOf[io.Writer](), Lifetime_Transient, func(c Container) (any, error) {
+		return 1, nil
+	}))
+	AddSingletonFunc[*strings.Reader](b, func(c Container) (*strings.Reader, error) {
+		return nil, errors.New("failed")
+	})
+	AddScopedFunc[*strings.Builder](b, func(c Container) (*strings.Builder, error) {
+		return &strings.Builder{}, nil
+	})
+
+	c := b.Build()
+	if _, err := TryGet[io.Reader](c); err == nil || err.Error() != "failed" {
+		t.Error("expect the error of the factory")
+	}
+	if _, err := TryGet[io.Writer](c); err == nil {
+		t.Error("expect an error for the instance not assignable")
+	}
+	if _, err := TryGet[*strings.Reader](c); err == nil || err.Error() != "failed" {
+		t.Error("expect the error of the factory")
+	}
+
+	scope := Get[ScopeFactory](c).CreateScope()
+	if Get[*strings.Builder](scope.Container()) != Get[*strings.Builder](scope.Container()) {
+		t.Error("assertion failed")
+	}
+}
+
+func TestFactory_Enumerable(t *testing.T) {
+	newA := func(c Container) (string, error) { return "a", nil }
+	b := Builder()
+	b.TryAddEnumerable(SingletonFunc[string](newA))
+	b.TryAddEnumerable(SingletonFunc[string](newA))
+	b.TryAddEnumerable(SingletonFunc[string](func(c Container) (string, error) { return "b", nil }))
+
+	if v := Get[[]string](b.Build()); len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Errorf("expect the factory functions compared, actual %v", v)
+	}
+}
@@ -0,0 +1,99 @@
+package di
+
+// The call sites that the call site depends on directly,
+// the services resolved on demand by Lazy and providers are not included.
+func callSiteDependencies(callSite CallSite) []CallSite {
+	switch cs := callSite.(type) {
+	case *ConstructorCallSite:
+		return cs.Parameters
+	case *StructCallSite:
+		return nonNilCallSites(cs.Fields)
+	case *DecoratorCallSite:
+		return append([]CallSite{cs.Inner}, cs.Parameters...)
+	case *OptionalCallSite:
+		if cs.Inner != nil {
+			return []CallSite{cs.Inner}
+		}
+	case *SliceCallSite:
+		return cs.CallSites
+	case *AssistedCallSite:
+		return nonNilCallSites(cs.Parameters)
+	case *ActivationCallSite:
+		return []CallSite{cs.Inner}
+	}
+	return nil
+}
+
+func nonNilCallSites(callSites []CallSite) []CallSite {
+	result := make([]CallSite, 0, len(callSites))
+	for _, cs := range callSites {
+		if cs != nil {
+			result = append(result, cs)
+		}
+	}
+	return result
+}
+
+// Determines if the instance of the call site is shared by the root scope,
+// including the managed services validated as singletons, except the pooled ones.
+func isSingletonCallSite(callSite CallSite) bool {
+	cache := callSite.Cache()
+	if cache.Location == CacheLocation_Managed {
+		_, pooled := cache.Manager.(*pool)
+		return !pooled && cache.Manager.Lifetime() == Lifetime_Singleton
+	}
+	return cache.Location == CacheLocation_Root || callSite.Kind() == CallSiteKind_Constant
+}
+
+// The nearest singleton call sites that the call site depends on,
+// directly or through the call sites that are not singletons.
+func singletonDependencies(callSite CallSite) []CallSite {
+	result := make([]CallSite, 0)
+	visited := make(map[CallSite]bool)
+
+	var visit func(cs CallSite)
+	visit = func(cs CallSite) {
+		for _, dep := range callSiteDependencies(cs) {
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+
+			if isSingletonCallSite(dep) {
+				result = append(result, dep)
+			} else {
+				visit(dep)
+			}
+		}
+	}
+
+	visit(callSite)
+	return result
+}
+
+// The singleton call sites reachable from the roots, each of them follows its singleton dependencies,
+// including the ones depended on through the transient call sites.
+func sortSingletonCallSites(roots []CallSite) []CallSite {
+	sorted := make([]CallSite, 0, len(roots))
+	visited := make(map[CallSite]bool)
+
+	var visit func(cs CallSite)
+	visit = func(cs CallSite) {
+		if visited[cs] {
+			return
+		}
+		visited[cs] = true
+
+		for _, dep := range callSiteDependencies(cs) {
+			visit(dep)
+		}
+		if isSingletonCallSite(cs) {
+			sorted = append(sorted, cs)
+		}
+	}
+
+	for _, cs := range roots {
+		visit(cs)
+	}
+	return sorted
+}
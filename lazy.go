@@ -0,0 +1,71 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/dozm/di/reflectx"
+)
+
+// Lazy dependency of the service T, the service is resolved on the first call of Value
+// from the scope that the Lazy was injected from.
+// It can be used to break a circular dependency.
+//
+//	func NewReporter(db di.Lazy[*sql.DB]) *Reporter {
+//		return &Reporter{db: db}
+//	}
+type Lazy[T any] struct {
+	state *lazyState
+}
+
+type lazyState struct {
+	once    sync.Once
+	resolve func() (any, error)
+	value   any
+	err     error
+}
+
+// Resolve the service T on the first call, the result is cached for the subsequent calls.
+func (l Lazy[T]) Value() (result T, err error) {
+	if l.state == nil {
+		err = errors.New("the lazy value is not injected by the container")
+		return
+	}
+
+	l.state.once.Do(func() {
+		l.state.value, l.state.err = l.state.resolve()
+		l.state.resolve = nil
+	})
+
+	if l.state.err != nil {
+		return result, l.state.err
+	}
+
+	result, _ = l.state.value.(T)
+	return
+}
+
+func (Lazy[T]) elem() reflect.Type {
+	return reflectx.TypeOf[T]()
+}
+
+func (Lazy[T]) bind(resolve func() (any, error)) any {
+	return Lazy[T]{state: &lazyState{resolve: resolve}}
+}
+
+type lazy interface {
+	elem() reflect.Type
+	bind(resolve func() (any, error)) any
+}
+
+var lazyType = reflectx.TypeOf[lazy]()
+
+func isLazyType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(lazyType)
+}
+
+// the service type of the lazy type t.
+func lazyElem(t reflect.Type) reflect.Type {
+	return reflect.Zero(t).Interface().(lazy).elem()
+}
@@ -0,0 +1,187 @@
+package di
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dozm/di/errorx"
+)
+
+type lazyNode struct {
+	next Lazy[*lazyLeaf]
+}
+
+type lazyLeaf struct {
+	node *lazyNode
+}
+
+func TestLazy_CircularDependency(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateOnBuild = true
+	})
+	AddSingleton[*lazyNode](b, func(next Lazy[*lazyLeaf]) *lazyNode { return &lazyNode{next: next} })
+	AddSingleton[*lazyLeaf](b, func(node *lazyNode) *lazyLeaf { return &lazyLeaf{node: node} })
+
+	c := b.Build()
+
+	node := Get[*lazyNode](c)
+	leaf, err := node.next.Value()
+	if err != nil || leaf.node != node || leaf != Get[*lazyLeaf](c) {
+		t.Error("assertion failed")
+	}
+}
+
+func TestLazy_Scope(t *testing.T) {
+	count := int32(0)
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct {
+		return &DisposableStruct{Value: int(atomic.AddInt32(&count, 1))}
+	})
+	AddTransient[*[]Lazy[*DisposableStruct]](b, func(l Lazy[*DisposableStruct]) *[]Lazy[*DisposableStruct] {
+		return &[]Lazy[*DisposableStruct]{l}
+	})
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+
+	l := (*Get[*[]Lazy[*DisposableStruct]](scope.Container()))[0]
+	if count != 0 {
+		t.Error("expect not resolved before the first call")
+	}
+
+	v1, _ := l.Value()
+	v2, _ := l.Value()
+	if v1 != v2 || v1 != Get[*DisposableStruct](scope.Container()) || count != 1 {
+		t.Error("assertion failed")
+	}
+
+	scope.Dispose()
+	if !v1.Disposed {
+		t.Error("expect disposed with the scope")
+	}
+
+	var empty Lazy[int]
+	if _, err := empty.Value(); err == nil {
+		t.Error("assertion failed")
+	}
+}
+
+func TestLazy_NotRegistered(t *testing.T) {
+	b := Builder()
+	AddTransient[int](b, func(s Lazy[string]) int { return 1 })
+
+	if _, err := TryGet[int](b.Build()); err == nil {
+		t.Error("expect an error for the service not registered")
+	}
+}
+
+type lazyConsumer struct {
+	Value *DisposableStruct
+}
+
+func TestLazy_ValueDuringConstruction(t *testing.T) {
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	AddScoped[*lazyConsumer](b, func(l Lazy[*DisposableStruct]) (*lazyConsumer, error) {
+		v, err := l.Value()
+		return &lazyConsumer{Value: v}, err
+	})
+	AddScoped[*lazyNode](b, func(l Lazy[*lazyNode]) (*lazyNode, error) {
+		_, err := l.Value()
+		return &lazyNode{}, err
+	})
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+
+	done := make(chan *lazyConsumer)
+	go func() {
+		done <- Get[*lazyConsumer](scope.Container())
+	}()
+
+	select {
+	case consumer := <-done:
+		if consumer.Value != Get[*DisposableStruct](scope.Container()) {
+			t.Error("expect the lazy value resolved from the same scope")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect no deadlock on the scope lock")
+	}
+
+	if _, err := TryGet[*lazyNode](scope.Container()); err == nil {
+		t.Error("expect error if the service is resolved while it's being constructed")
+	}
+}
+
+func TestLazy_ValueInGoroutine(t *testing.T) {
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	AddScoped[*lazyConsumer](b, func(l Lazy[*DisposableStruct]) (*lazyConsumer, error) {
+		done := make(chan error)
+		go func() {
+			_, err := l.Value()
+			done <- err
+		}()
+		v, err := l.Value()
+		if err == nil {
+			err = <-done
+		}
+		return &lazyConsumer{Value: v}, err
+	})
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	defer scope.Dispose()
+
+	consumer, err := TryGet[*lazyConsumer](scope.Container())
+	if err != nil || consumer.Value != Get[*DisposableStruct](scope.Container()) {
+		t.Error("assertion failed")
+	}
+}
+
+func TestLazy_SingletonValueDuringConstruction(t *testing.T) {
+	b := Builder()
+	AddSingleton[*lazyNode](b, func(next Lazy[*lazyLeaf]) (*lazyNode, error) {
+		_, err := next.Value()
+		return &lazyNode{next: next}, err
+	})
+	AddSingleton[*lazyLeaf](b, func(node *lazyNode) *lazyLeaf { return &lazyLeaf{node: node} })
+
+	c := b.Build()
+
+	done := make(chan error)
+	go func() {
+		_, err := TryGet[*lazyNode](c)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		var circular *errorx.CircularDependencyError
+		if !errors.As(err, &circular) {
+			t.Error("expect circular dependency error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect no deadlock on the call site lock")
+	}
+}
+
+func TestLazy_ValidateScopes(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = true
+		o.ValidateOnBuild = true
+	})
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	AddSingleton[*lazyConsumer](b, func(l Lazy[*DisposableStruct]) *lazyConsumer { return &lazyConsumer{} })
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expect panic if a singleton depends on a lazy scoped service")
+		}
+	}()
+	b.Build()
+}
@@ -0,0 +1,193 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/dozm/di/errorx"
+	"github.com/dozm/di/reflectx"
+)
+
+// A singleton service implements Starter is started by Start after its dependencies.
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// A singleton service implements Stopper is stopped by Stop before its dependencies.
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// Hook of the Lifecycle, either of the functions can be nil.
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle is a built-in service to append the hooks from the constructors,
+// the hooks are started in the order they're appended and stopped in the reverse order.
+//
+//	func NewServer(lc di.Lifecycle) *Server {
+//		s := &Server{}
+//		lc.Append(di.Hook{OnStart: s.listen, OnStop: s.shutdown})
+//		return s
+//	}
+type Lifecycle interface {
+	Append(hook Hook)
+}
+
+var LifecycleType = reflectx.TypeOf[Lifecycle]()
+
+type lifecycle struct {
+	mu    sync.Mutex
+	hooks []Hook
+	// the number of the hooks started.
+	started int
+	// the call sites whose services have been appended.
+	appended map[CallSite]bool
+}
+
+func (l *lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// append the hook of the service of the call site if it implements Starter or Stopper.
+func (l *lifecycle) appendService(callSite CallSite, service any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.appended[callSite] {
+		return
+	}
+	l.appended[callSite] = true
+
+	var hook Hook
+	t := reflect.TypeOf(service)
+	if s, ok := service.(Starter); ok {
+		hook.OnStart = func(ctx context.Context) error {
+			if err := s.Start(ctx); err != nil {
+				return fmt.Errorf("failed to start the service '%v': %w", t, err)
+			}
+			return nil
+		}
+	}
+	if s, ok := service.(Stopper); ok {
+		hook.OnStop = func(ctx context.Context) error {
+			if err := s.Stop(ctx); err != nil {
+				return fmt.Errorf("failed to stop the service '%v': %w", t, err)
+			}
+			return nil
+		}
+	}
+
+	if hook.OnStart != nil || hook.OnStop != nil {
+		l.hooks = append(l.hooks, hook)
+	}
+}
+
+// start the hooks not started yet in order,
+// the started hooks are stopped if any of them fails.
+func (l *lifecycle) start(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.started == len(l.hooks) {
+			l.mu.Unlock()
+			return nil
+		}
+		hook := l.hooks[l.started]
+		l.mu.Unlock()
+
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				if stopErr := l.stop(ctx); stopErr != nil {
+					return &errorx.AggregateError{Errors: []error{err, stopErr}}
+				}
+				return err
+			}
+		}
+
+		l.mu.Lock()
+		l.started++
+		l.mu.Unlock()
+	}
+}
+
+// stop the started hooks in the reverse order.
+func (l *lifecycle) stop(ctx context.Context) error {
+	errs := &errorx.AggregateError{}
+	for {
+		l.mu.Lock()
+		if l.started == 0 {
+			l.mu.Unlock()
+			return errs.OrNil()
+		}
+		l.started--
+		hook := l.hooks[l.started]
+		l.mu.Unlock()
+
+		if hook.OnStop != nil {
+			if err := hook.OnStop(ctx); err != nil {
+				errs.Add(err)
+			}
+		}
+	}
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{appended: make(map[CallSite]bool)}
+}
+
+// Start the singleton services implement Starter in the dependency order
+// and the hooks appended to the Lifecycle, c is the container returned by Build.
+// The singleton services are resolved before they're started.
+func Start(ctx context.Context, c Container) error {
+	root, err := rootContainerOf(c)
+	if err != nil {
+		return err
+	}
+
+	roots, descriptors, err := root.singletonCallSites(func(d *Descriptor) bool { return true })
+	if err != nil {
+		return err
+	}
+
+	for _, callSite := range sortSingletonCallSites(roots) {
+		// the singleton services of the parent container are started with the parent.
+		if _, ok := descriptors[callSite]; !ok && root.rootOf(callSite) != root.Root {
+			continue
+		}
+		service, err := CallSiteResolverInstance.Resolve(callSite, root.Root)
+		if err != nil {
+			return err
+		}
+		root.lifecycle.appendService(callSite, service)
+	}
+
+	return root.lifecycle.start(ctx)
+}
+
+// Stop the started services and hooks in the reverse order of starting,
+// c is the container returned by Build.
+func Stop(ctx context.Context, c Container) error {
+	root, err := rootContainerOf(c)
+	if err != nil {
+		return err
+	}
+
+	return root.lifecycle.stop(ctx)
+}
+
+func rootContainerOf(c Container) (*container, error) {
+	switch v := c.(type) {
+	case *container:
+		return v, nil
+	case *ContainerEngineScope:
+		if v.IsRootScope {
+			return v.RootContainer, nil
+		}
+	}
+	return nil, errorx.NewArgumentError("the container is not the root container")
+}
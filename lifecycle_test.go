@@ -0,0 +1,101 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type lifecycleEvents struct {
+	events []string
+}
+
+type lifecycleDB struct {
+	events *lifecycleEvents
+	err    error
+}
+
+func (db *lifecycleDB) Start(ctx context.Context) error {
+	db.events.events = append(db.events.events, "start db")
+	return db.err
+}
+
+func (db *lifecycleDB) Stop(ctx context.Context) error {
+	db.events.events = append(db.events.events, "stop db")
+	return nil
+}
+
+type lifecycleServer struct {
+	events *lifecycleEvents
+}
+
+func (s *lifecycleServer) Start(ctx context.Context) error {
+	s.events.events = append(s.events.events, "start server")
+	return nil
+}
+
+func (s *lifecycleServer) Stop(ctx context.Context) error {
+	s.events.events = append(s.events.events, "stop server")
+	return nil
+}
+
+func buildLifecycleContainer(events *lifecycleEvents, dbErr error) Container {
+	b := Builder()
+	// the server is registered before its dependencies.
+	AddSingleton[*lifecycleServer](b, func(db *lifecycleDB, lc Lifecycle) *lifecycleServer {
+		lc.Append(Hook{
+			OnStart: func(ctx context.Context) error {
+				events.events = append(events.events, "start hook")
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				events.events = append(events.events, "stop hook")
+				return nil
+			},
+		})
+		return &lifecycleServer{events: events}
+	})
+	AddTransient[*string](b, func(db *lifecycleDB) *string { return new(string) })
+	AddSingleton[*lifecycleDB](b, func() *lifecycleDB { return &lifecycleDB{events: events, err: dbErr} })
+	return b.Build()
+}
+
+func TestLifecycle(t *testing.T) {
+	events := &lifecycleEvents{}
+	c := buildLifecycleContainer(events, nil)
+
+	if err := Start(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := Stop(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"start db", "start hook", "start server", "stop server", "stop hook", "stop db"}
+	if len(events.events) != len(expected) {
+		t.Fatalf("expected %v actual %v", expected, events.events)
+	}
+	for i, e := range expected {
+		if events.events[i] != e {
+			t.Fatalf("expected %v actual %v", expected, events.events)
+		}
+	}
+}
+
+func TestLifecycle_StartError(t *testing.T) {
+	events := &lifecycleEvents{}
+	c := buildLifecycleContainer(events, errors.New("failed"))
+
+	if err := Start(context.Background(), c); err == nil {
+		t.Error("expect the error of starting")
+	}
+
+	if len(events.events) != 1 || events.events[0] != "start db" {
+		t.Errorf("expect the failed service not stopped, actual %v", events.events)
+	}
+
+	scope := Get[ScopeFactory](c).CreateScope()
+	if err := Start(context.Background(), scope.Container()); err == nil {
+		t.Error("expect an error for the container not root")
+	}
+}
@@ -0,0 +1,161 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/dozm/di/reflectx"
+)
+
+// LifetimeScope is the scope that a service with a custom lifetime is resolved in.
+type LifetimeScope interface {
+	// The Container that resolves the services from the scope.
+	Container() Container
+	// The root scope of the Container, the instances shared by all the scopes are owned by it.
+	RootScope() LifetimeScope
+}
+
+// LifetimeManager manages the instances of the services with a custom lifetime,
+// e.g. the instances shared per tenant or renewed after a period of time.
+// It's called concurrently by the scopes that resolve the service.
+type LifetimeManager interface {
+	// Get the instance of the service identified by key for the scope,
+	// or create one with the activator and decide which scope owns it for disposal.
+	// The key is an opaque comparable value that tells apart the services managed by the same manager.
+	Resolve(scope LifetimeScope, key any, activator Activator) (any, error)
+	// The built-in lifetime that the service is validated as,
+	// e.g. Lifetime_Singleton if an instance is shared by the scopes, so it can't depend on the scoped services.
+	Lifetime() Lifetime
+}
+
+// Activator creates and releases the instances of a service for its LifetimeManager.
+type Activator interface {
+	// Create an instance with the dependencies resolved from the scope, the instance is not captured for disposal,
+	// the cleanup returned by its constructor is called when the instance is disposed by Capture or Dispose.
+	Create(scope LifetimeScope) (any, error)
+	// Capture the instance to be disposed with the scope, the release hooks are called before the disposal.
+	Capture(scope LifetimeScope, instance any) error
+	// Call the release hooks and dispose the instance immediately.
+	Dispose(instance any) error
+	// Call fn when the scope is disposed, e.g. to return the instance to a pool instead of disposing it,
+	// the error returned by fn is reported by the disposal of the scope.
+	OnDispose(scope LifetimeScope, fn func() error) error
+}
+
+type activator struct {
+	resolver *CallSiteResolver
+	callSite CallSite
+	ctx      resolverContext
+}
+
+func (a *activator) Create(scope LifetimeScope) (any, error) {
+	ctx, err := a.contextOf(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var cleanups []func()
+	ctx.cleanups = &cleanups
+	instance, err := a.resolver.visitCallSiteMain(a.callSite, ctx)
+	cleanup := cleanupFunc(func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	})
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	if len(cleanups) == 0 {
+		return instance, nil
+	}
+
+	// the cleanup is held by the instance until the manager captures or disposes it,
+	// the cleanup of an instance that can't be looked up is captured by the scope that it's created in.
+	if instance == nil || !reflect.TypeOf(instance).Comparable() {
+		if err = a.resolver.captureDisposable(cleanup, ctx); err != nil {
+			return nil, err
+		}
+		return instance, nil
+	}
+	a.resolver.managedCleanups.Store(instance, cleanup)
+	return instance, nil
+}
+
+func (a *activator) Capture(scope LifetimeScope, instance any) error {
+	ctx, err := a.contextOf(scope)
+	if err != nil {
+		return err
+	}
+
+	// captured before the instance, so it's called after the instance is disposed.
+	if cleanup, ok := a.takeCleanup(instance); ok {
+		if err = a.resolver.captureDisposable(cleanup, ctx); err != nil {
+			return err
+		}
+	}
+	return a.resolver.captureCallSite(a.callSite, instance, ctx)
+}
+
+func (a *activator) Dispose(instance any) error {
+	if cs, ok := a.callSite.(*ActivationCallSite); ok {
+		for _, hook := range cs.Release {
+			hook(instance)
+		}
+	}
+
+	err := dispose(instance)
+	if cleanup, ok := a.takeCleanup(instance); ok {
+		cleanup()
+	}
+	return err
+}
+
+// take the cleanup held by the instance created by Create.
+func (a *activator) takeCleanup(instance any) (cleanupFunc, bool) {
+	if instance == nil || !reflect.TypeOf(instance).Comparable() {
+		return nil, false
+	}
+	return a.resolver.managedCleanups.LoadAndDelete(instance)
+}
+
+func (a *activator) OnDispose(scope LifetimeScope, fn func() error) error {
+	ctx, err := a.contextOf(scope)
+	if err != nil {
+		return err
+	}
+	return a.resolver.captureDisposable(managedCleanup(fn), ctx)
+}
+
+// the context to resolve in the scope, as part of the construction in progress.
+func (a *activator) contextOf(scope LifetimeScope) (resolverContext, error) {
+	s, ok := scope.(*ContainerEngineScope)
+	if !ok {
+		return resolverContext{}, fmt.Errorf("the scope '%T' is not created by the Container", scope)
+	}
+
+	if s == a.ctx.Scope {
+		return a.ctx, nil
+	}
+	return resolverContext{Scope: s, constructing: a.ctx.constructing}, nil
+}
+
+// Manage the instances of the service by the manager, the lifetime of the service becomes Lifetime_Custom.
+func ManagedBy(manager LifetimeManager) DescriptorOption {
+	return func(d *Descriptor) {
+		d.Lifetime = Lifetime_Custom
+		d.LifetimeManager = manager
+	}
+}
+
+// New a constructor descriptor of the service T whose instances are managed by the manager.
+func Managed[T any](manager LifetimeManager, ctor any, opts ...DescriptorOption) *Descriptor {
+	d := NewConstructorDescriptor(reflectx.TypeOf[T](), Lifetime_Custom, ctor)
+	ManagedBy(manager)(d)
+	return d.apply(opts)
+}
+
+// Add a constructor descriptor of the service T whose instances are managed by the manager to the ContainerBuilder.
+func AddManaged[T any](cb ContainerBuilder, manager LifetimeManager, ctor any, opts ...DescriptorOption) {
+	cb.Add(Managed[T](manager, ctor, opts...))
+}
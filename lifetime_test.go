@@ -0,0 +1,197 @@
+package di
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// shares an instance by the scopes and renews it after it's resolved max times.
+type renewingManager struct {
+	mu        sync.Mutex
+	max       int
+	uses      int
+	instance  any
+	lifetime  Lifetime
+	activated int
+}
+
+func (m *renewingManager) Resolve(scope LifetimeScope, key any, activator Activator) (any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.instance != nil && m.uses < m.max {
+		m.uses++
+		return m.instance, nil
+	}
+	if m.instance != nil {
+		if err := activator.Dispose(m.instance); err != nil {
+			return nil, err
+		}
+	}
+
+	instance, err := activator.Create(scope.RootScope())
+	if err != nil {
+		return nil, err
+	}
+	m.instance, m.uses = instance, 1
+	m.activated++
+	return instance, nil
+}
+
+func (m *renewingManager) Lifetime() Lifetime {
+	return m.lifetime
+}
+
+type managedService struct {
+	Closer   *closerStruct
+	Released bool
+}
+
+func TestLifetimeManager(t *testing.T) {
+	m := &renewingManager{max: 2, lifetime: Lifetime_Singleton}
+	b := Builder()
+	AddSingleton[*closerStruct](b, func() *closerStruct { return &closerStruct{} })
+	AddManaged[*managedService](b, m, func(c *closerStruct) *managedService {
+		return &managedService{Closer: c}
+	}, OnRelease(func(s *managedService) { s.Released = true }))
+
+	c := b.Build()
+	first := Get[*managedService](Get[ScopeFactory](c).CreateScope().Container())
+	if Get[*managedService](Get[ScopeFactory](c).CreateScope().Container()) != first {
+		t.Error("expect the instance shared by the scopes")
+	}
+	if first.Closer != Get[*closerStruct](c) {
+		t.Error("expect the dependencies resolved from the scope passed to the activator")
+	}
+
+	renewed := Get[*managedService](c)
+	if renewed == first || !first.Released || m.activated != 2 {
+		t.Error("expect the instance renewed by the manager")
+	}
+}
+
+func TestLifetimeManager_Validate(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = true
+	})
+	AddManaged[*managedService](b, &renewingManager{max: 1, lifetime: Lifetime_Scoped}, func() *managedService {
+		return &managedService{}
+	})
+
+	c := b.Build()
+	if _, err := TryGet[*managedService](c); err == nil {
+		t.Error("expect error if the service validated as scoped is resolved from the root scope")
+	}
+
+	b = Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = true
+		o.ValidateOnBuild = true
+	})
+	AddScoped[*closerStruct](b, func() *closerStruct { return &closerStruct{} })
+	AddManaged[*managedService](b, &renewingManager{max: 1, lifetime: Lifetime_Singleton}, func(c *closerStruct) *managedService {
+		return &managedService{Closer: c}
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expect panic if the service validated as singleton depends on a scoped service")
+		}
+	}()
+	b.Build()
+}
+
+type startedService struct {
+	Started bool
+}
+
+func (s *startedService) Start(ctx context.Context) error {
+	s.Started = true
+	return nil
+}
+
+func TestLifetimeManager_Singleton(t *testing.T) {
+	m := &renewingManager{max: 10, lifetime: Lifetime_Singleton}
+	b := Builder()
+	AddManaged[*startedService](b, m, func() *startedService { return &startedService{} }, Eager())
+
+	c := b.Build()
+	if m.activated != 1 {
+		t.Error("expect the managed singleton resolved eagerly")
+	}
+
+	if err := Start(context.Background(), c); err != nil || !Get[*startedService](c).Started {
+		t.Error("expect the managed singleton started")
+	}
+}
+
+// shares an instance per root scope.
+type rootManager struct {
+	mu        sync.Mutex
+	instances map[LifetimeScope]any
+}
+
+func (m *rootManager) Resolve(scope LifetimeScope, key any, activator Activator) (any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	root := scope.RootScope()
+	if instance, ok := m.instances[root]; ok {
+		return instance, nil
+	}
+
+	instance, err := activator.Create(root)
+	if err != nil {
+		return nil, err
+	}
+	if m.instances == nil {
+		m.instances = make(map[LifetimeScope]any)
+	}
+	m.instances[root] = instance
+	return instance, nil
+}
+
+func (m *rootManager) Lifetime() Lifetime {
+	return Lifetime_Singleton
+}
+
+func TestLifetimeManager_SingletonInChild(t *testing.T) {
+	b := Builder()
+	AddManaged[*managedService](b, &rootManager{}, func() *managedService { return &managedService{} })
+
+	c := b.Build()
+	child := Get[ChildFactory](c).CreateChild(func(cb ContainerBuilder) {
+		AddSingleton[*closerStruct](cb, func() *closerStruct { return &closerStruct{} })
+	})
+
+	if Get[*managedService](child) != Get[*managedService](c) {
+		t.Error("expect the managed singleton shared with the child container")
+	}
+	if Get[*managedService](Get[ScopeFactory](child).CreateScope().Container()) != Get[*managedService](c) {
+		t.Error("expect the managed singleton shared with the scopes of the child container")
+	}
+}
+
+func TestLifetimeManager_Cleanup(t *testing.T) {
+	m := &renewingManager{max: 1, lifetime: Lifetime_Singleton}
+	cleaned := 0
+	b := Builder()
+	AddManaged[*managedService](b, m, func() (*managedService, func()) {
+		return &managedService{}, func() { cleaned++ }
+	})
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	Get[*managedService](scope.Container())
+	scope.Dispose()
+	if cleaned != 0 {
+		t.Error("expect the cleanup not captured by the requesting scope")
+	}
+
+	Get[*managedService](c)
+	if cleaned != 1 {
+		t.Error("expect the cleanup called when the instance is disposed by the manager")
+	}
+}
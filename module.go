@@ -0,0 +1,85 @@
+package di
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dozm/di/errorx"
+)
+
+// A named, reusable group of registrations.
+//
+//	var StorageModule = &di.Module{
+//		Name:     "storage",
+//		Requires: []*di.Module{ConfigModule},
+//		Register: func(b di.ContainerBuilder) {
+//			di.AddSingleton[*Storage](b, NewStorage)
+//		},
+//	}
+type Module struct {
+	// the unique name of the module.
+	Name string
+	// the modules to be installed before this module.
+	Requires []*Module
+	// register the services of the module.
+	Register func(ContainerBuilder)
+}
+
+func (b *containerBuilder) Install(modules ...*Module) {
+	for _, m := range modules {
+		if err := b.install(m); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (b *containerBuilder) install(m *Module) error {
+	if m == nil || m.Name == "" {
+		return errorx.NewArgumentError("the module must have a name")
+	}
+
+	if installed, ok := b.installedModules[m.Name]; ok {
+		if installed != m {
+			return errorx.NewArgumentError(fmt.Sprintf("another module named '%v' is installed", m.Name))
+		}
+		return nil
+	}
+
+	for i, installing := range b.installingModules {
+		if installing.Name == m.Name {
+			return &errorx.CircularDependencyError{Message: b.createCircularModulesMessage(i, m)}
+		}
+	}
+
+	b.installingModules = append(b.installingModules, m)
+	defer func() { b.installingModules = b.installingModules[:len(b.installingModules)-1] }()
+
+	for _, required := range m.Requires {
+		if err := b.install(required); err != nil {
+			return err
+		}
+	}
+
+	if m.Register != nil {
+		m.Register(b)
+	}
+
+	if b.installedModules == nil {
+		b.installedModules = make(map[string]*Module)
+	}
+	b.installedModules[m.Name] = m
+	return nil
+}
+
+func (b *containerBuilder) createCircularModulesMessage(start int, m *Module) string {
+	var sb strings.Builder
+	sb.WriteString("a circular dependency was detected for the module '")
+	sb.WriteString(m.Name)
+	sb.WriteString("': ")
+	for _, installing := range b.installingModules[start:] {
+		sb.WriteString(installing.Name)
+		sb.WriteString(" -> ")
+	}
+	sb.WriteString(m.Name)
+	return sb.String()
+}
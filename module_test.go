@@ -0,0 +1,135 @@
+package di
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dozm/di/errorx"
+)
+
+func TestModule_Install(t *testing.T) {
+	order := make([]string, 0)
+	config := &Module{
+		Name: "config",
+		Register: func(b ContainerBuilder) {
+			order = append(order, "config")
+			AddInstance[string](b, "config")
+		},
+	}
+	storage := &Module{
+		Name:     "storage",
+		Requires: []*Module{config},
+		Register: func(b ContainerBuilder) {
+			order = append(order, "storage")
+			AddSingleton[int](b, func(s string) int { return len(s) })
+		},
+	}
+
+	b := Builder()
+	b.Install(storage, config)
+	b.Install(storage)
+
+	if strings.Join(order, ",") != "config,storage" {
+		t.Errorf("unexpected install order %v", order)
+	}
+
+	c := b.Build()
+	if v := Get[[]int](c); len(v) != 1 || v[0] != 6 {
+		t.Error("assertion failed")
+	}
+}
+
+func TestModule_Errors(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateOnBuild = true
+	})
+	b.Install(&Module{
+		Name: "broken",
+		Register: func(b ContainerBuilder) {
+			AddSingleton[int](b, func(s string) int { return len(s) })
+		},
+	})
+
+	var err error
+	func() {
+		defer func() { err, _ = recover().(error) }()
+		b.Build()
+	}()
+
+	var moduleErr *errorx.ModuleError
+	if aggregate, ok := err.(*errorx.AggregateError); ok && len(aggregate.Errors) == 1 {
+		errors.As(aggregate.Errors[0], &moduleErr)
+	}
+	if moduleErr == nil || moduleErr.Module != "broken" {
+		t.Errorf("expect an error of the module, actual %v", err)
+	}
+
+	failing := Transient[bool](func() (bool, error) { return false, errors.New("failed") })
+	b = Builder()
+	b.Install(&Module{
+		Name: "broken",
+		Register: func(b ContainerBuilder) {
+			AddSingleton[int](b, func(s string) int { return len(s) })
+			b.Add(failing)
+		},
+	})
+	if s := failing.String(); !strings.HasSuffix(s, "Module: broken") {
+		t.Errorf("expect the module in the description, actual %v", s)
+	}
+
+	container := b.Build()
+	for _, f := range []func() error{
+		func() error { _, err := TryGet[int](container); return err },
+		func() error { _, err := TryGet[bool](container); return err },
+	} {
+		moduleErr = nil
+		if err := f(); !errors.As(err, &moduleErr) || moduleErr.Module != "broken" {
+			t.Errorf("expect the error resolving the service annotated by the module, actual %v", err)
+		}
+	}
+
+	a := &Module{Name: "a"}
+	c := &Module{Name: "c", Requires: []*Module{a}}
+	a.Requires = []*Module{c}
+
+	func() {
+		defer func() {
+			if _, ok := recover().(*errorx.CircularDependencyError); !ok {
+				t.Error("assertion failed")
+			}
+		}()
+		Builder().Install(a)
+	}()
+}
+
+func TestModule_DependencyErrors(t *testing.T) {
+	storage := &Module{
+		Name: "storage",
+		Register: func(b ContainerBuilder) {
+			AddTransient[int](b, func() (int, error) { return 0, errors.New("failed") })
+			AddTransient[uint](b, func(s string) uint { return uint(len(s)) })
+		},
+	}
+	b := Builder()
+	b.Install(storage, &Module{
+		Name:     "report",
+		Requires: []*Module{storage},
+		Register: func(b ContainerBuilder) {
+			AddTransient[bool](b, func(n int) bool { return n > 0 })
+			AddTransient[float64](b, func(n uint) float64 { return float64(n) })
+		},
+	})
+
+	c := b.Build()
+	for _, f := range []func() error{
+		func() error { _, err := TryGet[bool](c); return err },
+		func() error { _, err := TryGet[float64](c); return err },
+	} {
+		var moduleErr *errorx.ModuleError
+		if err := f(); !errors.As(err, &moduleErr) || moduleErr.Module != "storage" {
+			t.Errorf("expect the error annotated by the module of the failing dependency, actual %v", err)
+		}
+	}
+}
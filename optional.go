@@ -0,0 +1,49 @@
+package di
+
+import (
+	"reflect"
+
+	"github.com/dozm/di/reflectx"
+)
+
+// Optional dependency of the service T, it's empty if the service T is not registered.
+//
+//	func NewHandler(cache di.Optional[Cache]) *Handler {
+//		if c, ok := cache.Value(); ok {
+//			...
+//		}
+//	}
+type Optional[T any] struct {
+	value T
+	ok    bool
+}
+
+// Get the value and whether the service is registered.
+func (o Optional[T]) Value() (T, bool) {
+	return o.value, o.ok
+}
+
+func (Optional[T]) elem() reflect.Type {
+	return reflectx.TypeOf[T]()
+}
+
+func (Optional[T]) with(value any) any {
+	v, _ := value.(T)
+	return Optional[T]{value: v, ok: true}
+}
+
+type optional interface {
+	elem() reflect.Type
+	with(value any) any
+}
+
+var optionalType = reflectx.TypeOf[optional]()
+
+func isOptionalType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(optionalType)
+}
+
+// the service type of the optional type t.
+func optionalElem(t reflect.Type) reflect.Type {
+	return reflect.Zero(t).Interface().(optional).elem()
+}
@@ -0,0 +1,92 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/dozm/di/reflectx"
+)
+
+func TestOptional(t *testing.T) {
+	type result struct {
+		name    string
+		hasName bool
+		count   int
+		hasInt  bool
+	}
+
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateOnBuild = true
+	})
+	AddKeyedInstance[string](b, "name", "a")
+	AddTransient[*result](b, func(name Optional[string], count Optional[int]) *result {
+		r := &result{}
+		r.name, r.hasName = name.Value()
+		r.count, r.hasInt = count.Value()
+		return r
+	}, ParamKey(0, "name"))
+
+	c := b.Build()
+
+	r := Get[*result](c)
+	if r.name != "a" || !r.hasName || r.count != 0 || r.hasInt {
+		t.Error("assertion failed")
+	}
+
+	if _, ok := Get[Optional[string]](c).Value(); ok {
+		t.Error("expect empty for the non-keyed service")
+	}
+
+	if !Get[IsService](c).IsService(reflectx.TypeOf[Optional[int]]()) {
+		t.Error("assertion failed")
+	}
+}
+
+func TestOptional_DependencyError(t *testing.T) {
+	b := Builder()
+	AddTransient[int](b, func(s string) int { return 1 })
+	AddTransient[bool](b, func(n Optional[int]) bool { return true })
+
+	if _, err := TryGet[bool](b.Build()); err == nil {
+		t.Error("expect an error for the missing dependency of the registered service")
+	}
+}
+
+func TestOptional_CallSiteCache(t *testing.T) {
+	b := Builder()
+	AddInstance[string](b, "a")
+	f := b.Build().(*container).CallSiteFactory
+
+	id := newServiceIdentifier(reflectx.TypeOf[Optional[string]](), nil)
+	cs1, err1 := f.GetCallSiteByIdentifier(id, newCallSiteChain())
+	cs2, err2 := f.GetCallSiteByIdentifier(id, newCallSiteChain())
+	if err1 != nil || err2 != nil || cs1 != cs2 {
+		t.Error("expect the optional call site cached")
+	}
+}
+
+func TestOptional_OnDemand(t *testing.T) {
+	b := Builder()
+	AddSingleton[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	c := b.Build()
+
+	if _, ok := Get[Optional[Lazy[*closerStruct]]](c).Value(); ok {
+		t.Error("expect empty if the lazy service is not registered")
+	}
+	if _, ok := Get[Optional[func() *closerStruct]](c).Value(); ok {
+		t.Error("expect empty if the provided service is not registered")
+	}
+
+	l, ok := Get[Optional[Lazy[*DisposableStruct]]](c).Value()
+	if !ok {
+		t.Fatal("expect the lazy service")
+	}
+	if v, err := l.Value(); err != nil || v != Get[*DisposableStruct](c) {
+		t.Error("assertion failed")
+	}
+
+	p, ok := Get[Optional[func() *DisposableStruct]](c).Value()
+	if !ok || p() != Get[*DisposableStruct](c) {
+		t.Error("assertion failed")
+	}
+}
@@ -0,0 +1,147 @@
+package di
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dozm/di/errorx"
+	"github.com/dozm/di/reflectx"
+)
+
+// Optional interface of a pooled service, Reset is called before the instance is returned to the pool.
+type Resettable interface {
+	Reset()
+}
+
+// The LifetimeManager of the pooled services, an instance is rented on each resolution
+// and returned to the pool when the scope that rented it is disposed.
+// The instances are disposed when the pool is full or the root scope is disposed.
+// Each root scope has its own idle instances, so the containers built from the same descriptor don't share them.
+type pool struct {
+	mu      sync.Mutex
+	maxSize int
+	roots   map[LifetimeScope]*poolState
+}
+
+// the idle instances of a root scope.
+type poolState struct {
+	idle     []any
+	disposed bool
+}
+
+func newPool(maxSize int) *pool {
+	return &pool{maxSize: maxSize, roots: make(map[LifetimeScope]*poolState)}
+}
+
+func (p *pool) Resolve(scope LifetimeScope, key any, activator Activator) (any, error) {
+	state, err := p.stateOf(scope.RootScope(), activator)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := p.rent(state, scope, activator)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = activator.OnDispose(scope, func() error { return p.put(state, instance, activator) }); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// the pooled instances are shared by the scopes over time, so they can't depend on the scoped services.
+func (p *pool) Lifetime() Lifetime {
+	return Lifetime_Singleton
+}
+
+// the state of the root scope, the idle instances are disposed with the root scope.
+func (p *pool) stateOf(root LifetimeScope, activator Activator) (*poolState, error) {
+	p.mu.Lock()
+	state, ok := p.roots[root]
+	if !ok {
+		state = &poolState{}
+		p.roots[root] = state
+	}
+	p.mu.Unlock()
+
+	if ok {
+		return state, nil
+	}
+	return state, activator.OnDispose(root, func() error { return p.dispose(root, state, activator) })
+}
+
+func (p *pool) rent(state *poolState, scope LifetimeScope, activator Activator) (any, error) {
+	p.mu.Lock()
+	if n := len(state.idle); n > 0 {
+		instance := state.idle[n-1]
+		state.idle[n-1] = nil
+		state.idle = state.idle[:n-1]
+		p.mu.Unlock()
+		return instance, nil
+	}
+	p.mu.Unlock()
+
+	return activator.Create(scope.RootScope())
+}
+
+// return the instance to the pool, it's disposed if the pool is full or disposed.
+func (p *pool) put(state *poolState, instance any, activator Activator) error {
+	if r, ok := instance.(Resettable); ok {
+		r.Reset()
+	}
+
+	p.mu.Lock()
+	if !state.disposed && len(state.idle) < p.maxSize {
+		state.idle = append(state.idle, instance)
+		p.mu.Unlock()
+		return nil
+	}
+	p.mu.Unlock()
+
+	return activator.Dispose(instance)
+}
+
+// dispose the idle instances of the root scope, the rented ones are disposed when they're returned.
+func (p *pool) dispose(root LifetimeScope, state *poolState, activator Activator) error {
+	p.mu.Lock()
+	idle := state.idle
+	state.idle = nil
+	state.disposed = true
+	delete(p.roots, root)
+	p.mu.Unlock()
+
+	errs := &errorx.AggregateError{}
+	for i := len(idle) - 1; i >= 0; i-- {
+		if err := activator.Dispose(idle[i]); err != nil {
+			errs.Add(err)
+		}
+	}
+	return errs.OrNil()
+}
+
+// Manage the instances of the service by a pool that keeps at most maxSize idle instances,
+// the lifetime of the service becomes Lifetime_Pooled.
+func PooledBy(maxSize int) DescriptorOption {
+	if maxSize < 1 {
+		panic(errorx.NewArgumentError(fmt.Sprintf("the max size of the pool must be positive, actual %v", maxSize)))
+	}
+
+	return func(d *Descriptor) {
+		d.Lifetime = Lifetime_Pooled
+		d.LifetimeManager = newPool(maxSize)
+	}
+}
+
+// New a pooled constructor descriptor of the service T, the pool keeps at most maxSize idle instances.
+func Pooled[T any](maxSize int, ctor any, opts ...DescriptorOption) *Descriptor {
+	d := NewConstructorDescriptor(reflectx.TypeOf[T](), Lifetime_Pooled, ctor)
+	PooledBy(maxSize)(d)
+	return d.apply(opts)
+}
+
+// Add a pooled constructor descriptor of the service T to the ContainerBuilder,
+// the pool keeps at most maxSize idle instances.
+func AddPooled[T any](cb ContainerBuilder, maxSize int, ctor any, opts ...DescriptorOption) {
+	cb.Add(Pooled[T](maxSize, ctor, opts...))
+}
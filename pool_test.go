@@ -0,0 +1,134 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dozm/di/errorx"
+)
+
+type pooledEncoder struct {
+	Resets   int
+	Disposed bool
+}
+
+func (e *pooledEncoder) Reset() {
+	e.Resets++
+}
+
+func (e *pooledEncoder) Dispose() {
+	e.Disposed = true
+}
+
+func TestPooled(t *testing.T) {
+	created := 0
+	b := Builder()
+	AddPooled[*pooledEncoder](b, 1, func() *pooledEncoder {
+		created++
+		return &pooledEncoder{}
+	})
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	e1 := Get[*pooledEncoder](scope.Container())
+	e2 := Get[*pooledEncoder](scope.Container())
+	if e1 == e2 || created != 2 {
+		t.Error("expect an instance rented on each resolution")
+	}
+
+	scope.Dispose()
+	if e1.Resets != 1 || e2.Resets != 1 {
+		t.Error("expect the instances reset when returned")
+	}
+	if e1.Disposed == e2.Disposed {
+		t.Error("expect the instance disposed when the pool overflows")
+	}
+	pooled := e1
+	if e1.Disposed {
+		pooled = e2
+	}
+
+	scope = Get[ScopeFactory](c).CreateScope()
+	if Get[*pooledEncoder](scope.Container()) != pooled || created != 2 {
+		t.Error("expect the returned instance rented again")
+	}
+
+	scope.Dispose()
+	if pooled.Disposed {
+		t.Error("expect the instance returned to the pool instead of disposed")
+	}
+
+	c.(DisposableWithError).Dispose()
+	if !pooled.Disposed {
+		t.Error("expect the pooled instance disposed with the container")
+	}
+}
+
+func TestPooled_Validate(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = true
+		o.ValidateOnBuild = true
+	})
+	AddScoped[*closerStruct](b, func() *closerStruct { return &closerStruct{} })
+	AddPooled[*managedService](b, 1, func(c *closerStruct) *managedService {
+		return &managedService{Closer: c}
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expect panic if a pooled service depends on a scoped service")
+		}
+	}()
+	b.Build()
+}
+
+type failingEncoder struct{}
+
+func (e *failingEncoder) Dispose() error {
+	return errors.New("failed")
+}
+
+func TestPooled_DisposeError(t *testing.T) {
+	b := Builder()
+	AddPooled[*failingEncoder](b, 1, func() *failingEncoder { return &failingEncoder{} })
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	Get[*failingEncoder](scope.Container())
+	Get[*failingEncoder](scope.Container())
+
+	var aggregate *errorx.AggregateError
+	var disposeErr *errorx.DisposeError
+	if err := scope.Dispose(); !errors.As(err, &aggregate) || len(aggregate.Errors) != 1 ||
+		!errors.As(aggregate.Errors[0], &disposeErr) || disposeErr.ServiceType != reflect.TypeOf(&failingEncoder{}) {
+		t.Error("expect the error of the instance disposed when the pool overflows")
+	}
+
+	if err := c.(DisposableWithError).Dispose(); !errors.As(err, &aggregate) || len(aggregate.Errors) != 1 ||
+		!errors.As(aggregate.Errors[0], &aggregate) || !errors.As(aggregate.Errors[0], &disposeErr) {
+		t.Error("expect the errors of the idle instances disposed with the container")
+	}
+}
+
+func TestPooled_Containers(t *testing.T) {
+	b := Builder()
+	AddPooled[*pooledEncoder](b, 1, func() *pooledEncoder { return &pooledEncoder{} })
+
+	for i := 0; i < 2; i++ {
+		c := b.Build()
+		scope := Get[ScopeFactory](c).CreateScope()
+		e := Get[*pooledEncoder](scope.Container())
+
+		scope.Dispose()
+		if e.Disposed {
+			t.Error("expect the instance returned to the pool of the container")
+		}
+
+		c.(DisposableWithError).Dispose()
+		if !e.Disposed {
+			t.Error("expect the pooled instance disposed with the container")
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package di
+
+import (
+	"fmt"
+
+	"github.com/dozm/di/reflectx"
+)
+
+// Add a service T constructed by ctor to the ContainerBuilder,
+// invoke calls the ctor with the resolved arguments without reflection.
+func provide[T any](cb ContainerBuilder, lifetime Lifetime, ctor any, invoke func(args []any) (any, error), opts []DescriptorOption) {
+	d := NewConstructorDescriptor(reflectx.TypeOf[T](), lifetime, ctor)
+	d.Ctor.invoke = invoke
+	cb.Add(d.apply(opts))
+}
+
+// the argument i asserted to the parameter type A, the zero value if it's a nil argument of an interface type,
+// err is set by the first argument that can't be asserted.
+func arg[A any](args []any, i int, err *error) A {
+	a, ok := args[i].(A)
+	if ok || *err != nil {
+		return a
+	}
+
+	if args[i] == nil {
+		if any(a) != nil {
+			*err = fmt.Errorf("the argument %v of the constructor is nil, but the parameter type '%v' is not an interface", i, reflectx.TypeOf[A]())
+		}
+	} else {
+		*err = fmt.Errorf("the argument %v of the constructor is a '%T', it can't be asserted to the parameter type '%v'", i, args[i], reflectx.TypeOf[A]())
+	}
+	return a
+}
+
+func result[T any](v T, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Add a service T constructed by the ctor with no dependency to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide0[T any](cb ContainerBuilder, lifetime Lifetime, ctor func() T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (any, error) {
+		return ctor(), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with no dependency to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide0E[T any](cb ContainerBuilder, lifetime Lifetime, ctor func() (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (any, error) {
+		return result(ctor())
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 1 dependency to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide1[T, A any](cb ContainerBuilder, lifetime Lifetime, ctor func(A) T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a := arg[A](args, 0, &err)
+		if err != nil {
+			return nil, err
+		}
+		return ctor(a), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 1 dependency to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide1E[T, A any](cb ContainerBuilder, lifetime Lifetime, ctor func(A) (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a := arg[A](args, 0, &err)
+		if err != nil {
+			return nil, err
+		}
+		return result(ctor(a))
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 2 dependencies to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide2[T, A, B any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B) T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b := arg[A](args, 0, &err), arg[B](args, 1, &err)
+		if err != nil {
+			return nil, err
+		}
+		return ctor(a, b), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 2 dependencies to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide2E[T, A, B any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B) (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b := arg[A](args, 0, &err), arg[B](args, 1, &err)
+		if err != nil {
+			return nil, err
+		}
+		return result(ctor(a, b))
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 3 dependencies to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide3[T, A, B, C any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C) T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err)
+		if err != nil {
+			return nil, err
+		}
+		return ctor(a, b, c), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 3 dependencies to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide3E[T, A, B, C any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C) (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err)
+		if err != nil {
+			return nil, err
+		}
+		return result(ctor(a, b, c))
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 4 dependencies to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide4[T, A, B, C, D any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D) T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err)
+		if err != nil {
+			return nil, err
+		}
+		return ctor(a, b, c, d), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 4 dependencies to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide4E[T, A, B, C, D any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D) (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err)
+		if err != nil {
+			return nil, err
+		}
+		return result(ctor(a, b, c, d))
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 5 dependencies to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide5[T, A, B, C, D, E any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D, E) T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d, e := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err), arg[E](args, 4, &err)
+		if err != nil {
+			return nil, err
+		}
+		return ctor(a, b, c, d, e), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 5 dependencies to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide5E[T, A, B, C, D, E any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D, E) (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d, e := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err), arg[E](args, 4, &err)
+		if err != nil {
+			return nil, err
+		}
+		return result(ctor(a, b, c, d, e))
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 6 dependencies to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide6[T, A, B, C, D, E, F any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D, E, F) T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d, e, f := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err), arg[E](args, 4, &err), arg[F](args, 5, &err)
+		if err != nil {
+			return nil, err
+		}
+		return ctor(a, b, c, d, e, f), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 6 dependencies to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide6E[T, A, B, C, D, E, F any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D, E, F) (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d, e, f := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err), arg[E](args, 4, &err), arg[F](args, 5, &err)
+		if err != nil {
+			return nil, err
+		}
+		return result(ctor(a, b, c, d, e, f))
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 7 dependencies to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide7[T, A, B, C, D, E, F, G any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D, E, F, G) T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d, e, f, g := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err), arg[E](args, 4, &err), arg[F](args, 5, &err), arg[G](args, 6, &err)
+		if err != nil {
+			return nil, err
+		}
+		return ctor(a, b, c, d, e, f, g), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 7 dependencies to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide7E[T, A, B, C, D, E, F, G any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D, E, F, G) (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d, e, f, g := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err), arg[E](args, 4, &err), arg[F](args, 5, &err), arg[G](args, 6, &err)
+		if err != nil {
+			return nil, err
+		}
+		return result(ctor(a, b, c, d, e, f, g))
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 8 dependencies to the ContainerBuilder,
+// the ctor is checked by the compiler and called without reflection.
+func Provide8[T, A, B, C, D, E, F, G, H any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D, E, F, G, H) T, opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d, e, f, g, h := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err), arg[E](args, 4, &err), arg[F](args, 5, &err), arg[G](args, 6, &err), arg[H](args, 7, &err)
+		if err != nil {
+			return nil, err
+		}
+		return ctor(a, b, c, d, e, f, g, h), nil
+	}, opts)
+}
+
+// Add a service T constructed by the ctor with 8 dependencies to the ContainerBuilder,
+// the error returned by the ctor is propagated to the caller.
+func Provide8E[T, A, B, C, D, E, F, G, H any](cb ContainerBuilder, lifetime Lifetime, ctor func(A, B, C, D, E, F, G, H) (T, error), opts ...DescriptorOption) {
+	provide[T](cb, lifetime, ctor, func(args []any) (_ any, err error) {
+		a, b, c, d, e, f, g, h := arg[A](args, 0, &err), arg[B](args, 1, &err), arg[C](args, 2, &err), arg[D](args, 3, &err), arg[E](args, 4, &err), arg[F](args, 5, &err), arg[G](args, 6, &err), arg[H](args, 7, &err)
+		if err != nil {
+			return nil, err
+		}
+		return result(ctor(a, b, c, d, e, f, g, h))
+	}, opts)
+}
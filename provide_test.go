@@ -0,0 +1,136 @@
+package di
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+type provideStruct struct {
+	Value int
+	Name  string
+}
+
+func TestProvide(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateOnBuild = true
+	})
+	Provide0[int](b, Lifetime_Singleton, func() int { return 1 })
+	Provide0[string](b, Lifetime_Singleton, func() string { return "a" })
+	Provide2[*provideStruct](b, Lifetime_Transient, func(i int, s string) *provideStruct {
+		return &provideStruct{Value: i, Name: s}
+	})
+	AddKeyedSingleton[int](b, "two", func() int { return 2 })
+	Provide1[int8](b, Lifetime_Transient, func(i int) int8 { return int8(i) }, ParamKey(0, "two"))
+	Provide3E[int16](b, Lifetime_Transient, func(i int, s string, i8 int8) (int16, error) {
+		return 0, errors.New("failed")
+	})
+
+	c := b.Build()
+	if v := Get[*provideStruct](c); v.Value != 1 || v.Name != "a" {
+		t.Error("assertion failed")
+	}
+	if Get[int8](c) != 2 {
+		t.Error("expect the keyed parameter")
+	}
+	if _, err := TryGet[int16](c); err == nil || err.Error() != "failed" {
+		t.Error("expect the error of the constructor")
+	}
+}
+
+func TestProvide_Arities(t *testing.T) {
+	cases := []struct {
+		name    string
+		want    int64
+		provide func(b ContainerBuilder)
+	}{
+		{"Provide0", 0, func(b ContainerBuilder) {
+			Provide0[int64](b, Lifetime_Transient, func() int64 { return int64(0) })
+		}},
+		{"Provide0E", 0, func(b ContainerBuilder) {
+			Provide0E[int64](b, Lifetime_Transient, func() (int64, error) { return int64(0), nil })
+		}},
+		{"Provide1", 1, func(b ContainerBuilder) {
+			Provide1[int64, int](b, Lifetime_Transient, func(a int) int64 { return int64(a) })
+		}},
+		{"Provide1E", 1, func(b ContainerBuilder) {
+			Provide1E[int64, int](b, Lifetime_Transient, func(a int) (int64, error) { return int64(a), nil })
+		}},
+		{"Provide2", 2, func(b ContainerBuilder) {
+			Provide2[int64, int, int](b, Lifetime_Transient, func(a int, b int) int64 { return int64(a + b) })
+		}},
+		{"Provide2E", 2, func(b ContainerBuilder) {
+			Provide2E[int64, int, int](b, Lifetime_Transient, func(a int, b int) (int64, error) { return int64(a + b), nil })
+		}},
+		{"Provide3", 3, func(b ContainerBuilder) {
+			Provide3[int64, int, int, int](b, Lifetime_Transient, func(a int, b int, c int) int64 { return int64(a + b + c) })
+		}},
+		{"Provide3E", 3, func(b ContainerBuilder) {
+			Provide3E[int64, int, int, int](b, Lifetime_Transient, func(a int, b int, c int) (int64, error) { return int64(a + b + c), nil })
+		}},
+		{"Provide4", 4, func(b ContainerBuilder) {
+			Provide4[int64, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int) int64 { return int64(a + b + c + d) })
+		}},
+		{"Provide4E", 4, func(b ContainerBuilder) {
+			Provide4E[int64, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int) (int64, error) { return int64(a + b + c + d), nil })
+		}},
+		{"Provide5", 5, func(b ContainerBuilder) {
+			Provide5[int64, int, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int, e int) int64 { return int64(a + b + c + d + e) })
+		}},
+		{"Provide5E", 5, func(b ContainerBuilder) {
+			Provide5E[int64, int, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int, e int) (int64, error) { return int64(a + b + c + d + e), nil })
+		}},
+		{"Provide6", 6, func(b ContainerBuilder) {
+			Provide6[int64, int, int, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int, e int, f int) int64 { return int64(a + b + c + d + e + f) })
+		}},
+		{"Provide6E", 6, func(b ContainerBuilder) {
+			Provide6E[int64, int, int, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int, e int, f int) (int64, error) {
+				return int64(a + b + c + d + e + f), nil
+			})
+		}},
+		{"Provide7", 7, func(b ContainerBuilder) {
+			Provide7[int64, int, int, int, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int, e int, f int, g int) int64 { return int64(a + b + c + d + e + f + g) })
+		}},
+		{"Provide7E", 7, func(b ContainerBuilder) {
+			Provide7E[int64, int, int, int, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int, e int, f int, g int) (int64, error) {
+				return int64(a + b + c + d + e + f + g), nil
+			})
+		}},
+		{"Provide8", 8, func(b ContainerBuilder) {
+			Provide8[int64, int, int, int, int, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int, e int, f int, g int, h int) int64 {
+				return int64(a + b + c + d + e + f + g + h)
+			})
+		}},
+		{"Provide8E", 8, func(b ContainerBuilder) {
+			Provide8E[int64, int, int, int, int, int, int, int, int](b, Lifetime_Transient, func(a int, b int, c int, d int, e int, f int, g int, h int) (int64, error) {
+				return int64(a + b + c + d + e + f + g + h), nil
+			})
+		}},
+	}
+
+	for _, c := range cases {
+		b := Builder()
+		AddInstance[int](b, 1)
+		c.provide(b)
+		if v, err := TryGet[int64](b.Build()); err != nil || v != c.want {
+			t.Errorf("%v: expected %v actual %v %v", c.name, c.want, v, err)
+		}
+	}
+}
+
+func TestProvide_ArgumentType(t *testing.T) {
+	b := Builder()
+	b.Add(SingletonFactory[*provideStruct](func(c Container) any { return nil }))
+	b.Add(SingletonFactory[io.Reader](func(c Container) any { return nil }))
+	Provide1[int](b, Lifetime_Transient, func(p *provideStruct) int { return 1 })
+	Provide1[string](b, Lifetime_Transient, func(r io.Reader) string { return "a" })
+
+	c := b.Build()
+	if _, err := TryGet[int](c); err == nil {
+		t.Error("expect an error for the nil argument of a non-interface parameter")
+	}
+	if v, err := TryGet[string](c); err != nil || v != "a" {
+		t.Error("expect the nil argument of an interface parameter")
+	}
+}
@@ -0,0 +1,111 @@
+package di
+
+import (
+	"reflect"
+
+	"github.com/dozm/di/reflectx"
+)
+
+// Provider of the service T, each call of Get resolves the service T
+// from the scope that the Provider was injected from.
+// The function types func() T and func() (T, error) can be injected as well.
+//
+//	func NewWorker(newJob di.Provider[*Job]) *Worker {
+//		return &Worker{newJob: newJob}
+//	}
+type Provider[T any] interface {
+	Get() (T, error)
+	impl(*provider[T])
+}
+
+type provider[T any] struct {
+	resolve func() (any, error)
+}
+
+func (p *provider[T]) Get() (result T, err error) {
+	v, err := p.resolve()
+	if err != nil {
+		return
+	}
+
+	result, _ = v.(T)
+	return
+}
+
+func (*provider[T]) impl(*provider[T]) {}
+
+func (*provider[T]) bind(resolve func() (any, error)) any {
+	return &provider[T]{resolve: resolve}
+}
+
+type providerBinder interface {
+	bind(resolve func() (any, error)) any
+}
+
+var providerBinderType = reflectx.TypeOf[providerBinder]()
+
+// the service type provided by the provider type t,
+// t is a Provider[T], a func() T or a func() (T, error).
+func providerElem(t reflect.Type) (reflect.Type, bool) {
+	switch t.Kind() {
+	case reflect.Interface:
+		if _, ok := providerImplType(t); ok {
+			return t.Method(0).Type.Out(0), true
+		}
+	case reflect.Func:
+		if t.NumIn() == 0 && !t.IsVariadic() &&
+			(t.NumOut() == 1 || (t.NumOut() == 2 && reflectx.IsErrorType(t.Out(1)))) {
+			return t.Out(0), true
+		}
+	}
+	return nil, false
+}
+
+// the implementation type of the Provider type t.
+func providerImplType(t reflect.Type) (reflect.Type, bool) {
+	m, ok := t.MethodByName("impl")
+	if !ok || m.PkgPath != providerBinderType.PkgPath() || t.NumMethod() != 2 {
+		return nil, false
+	}
+
+	impl := m.Type.In(0)
+	return impl, impl.Implements(providerBinderType)
+}
+
+// Create a provider of the type t that calls resolve to get the service.
+func newProvider(t reflect.Type, resolve func() (any, error)) any {
+	if impl, ok := providerImplType(t); ok {
+		return reflect.Zero(impl).Interface().(providerBinder).bind(resolve)
+	}
+
+	return reflect.MakeFunc(t, func([]reflect.Value) []reflect.Value {
+		v, err := resolve()
+		return funcResults(t, v, err)
+	}).Interface()
+}
+
+// the results of the function type t that returns (T) or (T, error),
+// it panics with the error if t doesn't return an error.
+func funcResults(t reflect.Type, v any, err error) []reflect.Value {
+	elem := t.Out(0)
+	if t.NumOut() == 1 {
+		if err != nil {
+			panic(err)
+		}
+		return []reflect.Value{valueOf(v, elem)}
+	}
+
+	errValue := reflect.Zero(t.Out(1))
+	if err != nil {
+		return []reflect.Value{reflect.Zero(elem), reflect.ValueOf(err)}
+	}
+	return []reflect.Value{valueOf(v, elem), errValue}
+}
+
+// the reflect value of v, the zero value of t if v is nil.
+func valueOf(v any, t reflect.Type) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(v)
+}
@@ -0,0 +1,143 @@
+package di
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type providerWorker struct {
+	newStruct      func() *DisposableStruct
+	tryNewStruct   func() (*DisposableStruct, error)
+	structProvider Provider[*DisposableStruct]
+}
+
+func TestProvider(t *testing.T) {
+	count := 0
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateOnBuild = true
+	})
+	AddTransient[*DisposableStruct](b, func() *DisposableStruct {
+		count++
+		return &DisposableStruct{Value: count}
+	})
+	AddSingleton[*providerWorker](b, func(
+		newStruct func() *DisposableStruct,
+		tryNewStruct func() (*DisposableStruct, error),
+		structProvider Provider[*DisposableStruct],
+	) *providerWorker {
+		return &providerWorker{newStruct, tryNewStruct, structProvider}
+	})
+
+	c := b.Build()
+	w := Get[*providerWorker](c)
+	if count != 0 {
+		t.Error("expect not resolved before the first call")
+	}
+
+	v1 := w.newStruct()
+	v2, err := w.tryNewStruct()
+	v3, err3 := w.structProvider.Get()
+	if err != nil || err3 != nil || v1 == v2 || v2 == v3 || v3.Value != 3 {
+		t.Error("assertion failed")
+	}
+
+	c.(DisposableWithError).Dispose()
+	if !v1.Disposed || !v2.Disposed || !v3.Disposed {
+		t.Error("expect disposed with the root scope")
+	}
+}
+
+func TestProvider_RegisteredFunc(t *testing.T) {
+	b := Builder()
+	AddInstance[func() int](b, func() int { return 2 })
+	AddTransient[int](b, func() int { return 1 })
+	AddTransient[string](b, func(f func() int) string {
+		if f() == 2 {
+			return "registered"
+		}
+		return "provider"
+	})
+
+	if Get[string](b.Build()) != "registered" {
+		t.Error("expect the registered function wins")
+	}
+}
+
+func TestProvider_Error(t *testing.T) {
+	b := Builder()
+	AddTransient[int](b, func() (int, error) { return 0, errors.New("failed") })
+	AddTransient[*func() (int, error)](b, func(f func() (int, error)) *func() (int, error) { return &f })
+	AddTransient[string](b, func(f func() bool) string { return "" })
+
+	c := b.Build()
+	if _, err := (*Get[*func() (int, error)](c))(); err == nil {
+		t.Error("expect the error of the constructor")
+	}
+	if _, err := TryGet[string](c); err == nil {
+		t.Error("expect an error for the service not registered")
+	}
+}
+
+type providerConsumer struct {
+	Values []*DisposableStruct
+}
+
+func TestProvider_CallDuringConstruction(t *testing.T) {
+	count := 0
+	b := Builder()
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct {
+		count++
+		return &DisposableStruct{Value: count}
+	})
+	AddTransient[*closerStruct](b, func() *closerStruct { return &closerStruct{} })
+	AddScoped[*providerConsumer](b, func(
+		newStruct func() *DisposableStruct,
+		structProvider Provider[*DisposableStruct],
+		newCloser func() (*closerStruct, error),
+	) (*providerConsumer, error) {
+		v, err := structProvider.Get()
+		if _, e := newCloser(); e != nil {
+			err = e
+		}
+		return &providerConsumer{Values: []*DisposableStruct{newStruct(), v}}, err
+	})
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+
+	done := make(chan *providerConsumer)
+	go func() {
+		done <- Get[*providerConsumer](scope.Container())
+	}()
+
+	select {
+	case consumer := <-done:
+		v := Get[*DisposableStruct](scope.Container())
+		if consumer.Values[0] != v || consumer.Values[1] != v || count != 1 {
+			t.Error("expect the provided services resolved from the same scope")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect no deadlock on the scope lock")
+	}
+}
+
+func TestProvider_ValidateScopes(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = true
+		o.ValidateOnBuild = true
+	})
+	AddScoped[*DisposableStruct](b, func() *DisposableStruct { return &DisposableStruct{} })
+	AddSingleton[*providerConsumer](b, func(newStruct func() *DisposableStruct) *providerConsumer {
+		return &providerConsumer{}
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expect panic if a singleton depends on a provider of a scoped service")
+		}
+	}()
+	b.Build()
+}
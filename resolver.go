@@ -4,26 +4,53 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync/atomic"
 
+	"github.com/dozm/di/errorx"
+	"github.com/dozm/di/reflectx"
 	"github.com/dozm/di/syncx"
 )
 
-type resolverLock byte
-
-const (
-	resolverLock_Scope resolverLock = 1
-	resolverLock_Root  resolverLock = 2
-)
-
 var CallSiteResolverInstance *CallSiteResolver = newCallSiteResolver()
 
 type resolverContext struct {
-	Scope         *ContainerEngineScope
-	AcquiredLocks resolverLock
+	Scope *ContainerEngineScope
+	// the construction in progress that the resolution is part of, nil if there is none.
+	constructing *construction
+	// the cleanups returned by the constructors of a managed instance, they're collected for its manager
+	// instead of being captured by the scope, nil if the instance is not managed.
+	cleanups *[]func()
+}
+
+// A call site being constructed in a scope. The services resolved on demand by the construction,
+// e.g. by Lazy.Value called in a constructor, can't depend on the call sites still being constructed.
+type construction struct {
+	callSite CallSite
+	scope    *ContainerEngineScope
+	parent   *construction
+	done     atomic.Bool
+}
+
+// Determines if the call site is still being constructed in the scope by the construction c or its parents.
+func (c *construction) isConstructing(callSite CallSite, scope *ContainerEngineScope) bool {
+	for ; c != nil; c = c.parent {
+		if c.callSite == callSite && c.scope == scope && !c.done.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+// the context to construct the call site in the scope with.
+func (ctx resolverContext) construct(callSite CallSite, scope *ContainerEngineScope) (resolverContext, *construction) {
+	c := &construction{callSite: callSite, scope: scope, parent: ctx.constructing}
+	return resolverContext{Scope: scope, constructing: c}, c
 }
 
 type CallSiteResolver struct {
 	callSiteLockers *syncx.LockMap
+	// the cleanups of the managed instances, until the instances are captured or disposed by their managers.
+	managedCleanups *syncx.Map[any, cleanupFunc]
 }
 
 func (r *CallSiteResolver) Resolve(callSite CallSite, scope *ContainerEngineScope) (any, error) {
@@ -36,7 +63,21 @@ func (r *CallSiteResolver) Resolve(callSite CallSite, scope *ContainerEngineScop
 	return r.visitCallSite(callSite, resolverContext{Scope: scope})
 }
 
+// visit the call site, the error is annotated by the module of the service that failed.
 func (r *CallSiteResolver) visitCallSite(callSite CallSite, ctx resolverContext) (any, error) {
+	if ctx.cleanups != nil && callSite.Cache().Location != CacheLocation_None {
+		// the dependencies of the managed instance are not part of it.
+		ctx.cleanups = nil
+	}
+
+	v, err := r.visitCallSiteCache(callSite, ctx)
+	if err != nil {
+		return nil, annotateModule(ctx.Scope.RootContainer.CallSiteFactory.moduleDescriptor(callSite), err)
+	}
+	return v, nil
+}
+
+func (r *CallSiteResolver) visitCallSiteCache(callSite CallSite, ctx resolverContext) (any, error) {
 	switch callSite.Cache().Location {
 	case CacheLocation_Root:
 		return r.visitRootCache(callSite, ctx)
@@ -46,6 +87,8 @@ func (r *CallSiteResolver) visitCallSite(callSite CallSite, ctx resolverContext)
 		return r.visitDisposeCache(callSite, ctx)
 	case CacheLocation_None:
 		return r.visitNoCache(callSite, ctx)
+	case CacheLocation_Managed:
+		return r.visitManagedCache(callSite, ctx)
 	default:
 		return nil, errors.New("unknow cache location")
 	}
@@ -63,6 +106,22 @@ func (r *CallSiteResolver) visitCallSiteMain(callSite CallSite, ctx resolverCont
 		return r.visitConstant(callSite.(*ConstantCallSite), ctx)
 	case CallSiteKind_Container:
 		return r.visitContainer(callSite.(*ContainerCallSite), ctx)
+	case CallSiteKind_Struct:
+		return r.visitStruct(callSite.(*StructCallSite), ctx)
+	case CallSiteKind_Decorator:
+		return r.visitDecorator(callSite.(*DecoratorCallSite), ctx)
+	case CallSiteKind_Optional:
+		return r.visitOptional(callSite.(*OptionalCallSite), ctx)
+	case CallSiteKind_Lazy:
+		return r.visitLazy(callSite.(*LazyCallSite), ctx)
+	case CallSiteKind_Provider:
+		return r.visitProvider(callSite.(*ProviderCallSite), ctx)
+	case CallSiteKind_Assisted:
+		return r.visitAssisted(callSite.(*AssistedCallSite), ctx)
+	case CallSiteKind_Activation:
+		return r.visitActivation(callSite.(*ActivationCallSite), ctx)
+	case CallSiteKind_ScopeLocal:
+		return r.visitScopeLocal(callSite.(*ScopeLocalCallSite), ctx)
 	default:
 		return nil, errors.New("unknow call site kind")
 	}
@@ -78,19 +137,63 @@ func (r *CallSiteResolver) visitDisposeCache(transientCallSite CallSite, ctx res
 		return nil, err
 	}
 
-	_, err = ctx.Scope.CaptureDisposable(v)
-	if err != nil {
+	if err = r.captureCallSite(transientCallSite, v, ctx); err != nil {
 		return nil, err
 	}
 
 	return v, nil
 }
 
+// capture the service resolved by the call site for disposal, and then the release hooks of the call site,
+// so the hooks are called before the service is disposed.
+func (r *CallSiteResolver) captureCallSite(callSite CallSite, service any, ctx resolverContext) error {
+	if cs, ok := callSite.(*DecoratorCallSite); ok && cs.Capture {
+		// captured by the decorator that created it.
+		return nil
+	}
+
+	if err := r.captureDisposable(service, ctx); err != nil {
+		return err
+	}
+
+	if cs, ok := callSite.(*ActivationCallSite); ok && len(cs.Release) > 0 {
+		return r.captureDisposable(cleanupFunc(func() {
+			for _, hook := range cs.Release {
+				hook(service)
+			}
+		}), ctx)
+	}
+	return nil
+}
+
+// capture the disposable service in the scope of the context.
+func (r *CallSiteResolver) captureDisposable(service any, ctx resolverContext) error {
+	return ctx.Scope.CaptureDisposable(service)
+}
+
 func (r *CallSiteResolver) visitFactory(callSite *FactoryCallSite, ctx resolverContext) (any, error) {
-	return callSite.Factory(ctx.Scope), nil
+	if callSite.FactoryFunc == nil {
+		return callSite.Factory(ctx.Scope), nil
+	}
+
+	v, err := callSite.FactoryFunc(ctx.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if v != nil {
+		if err = instanceAssignable(v, callSite.ServiceType()); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
 }
 
 func (r *CallSiteResolver) visitConstructor(callSite *ConstructorCallSite, ctx resolverContext) (any, error) {
+	if callSite.Ctor.invoke != nil {
+		return r.invoke(callSite, ctx)
+	}
+
 	numParams := len(callSite.Parameters)
 	inValues := make([]reflect.Value, numParams)
 	if numParams > 0 {
@@ -104,22 +207,227 @@ func (r *CallSiteResolver) visitConstructor(callSite *ConstructorCallSite, ctx r
 		}
 	}
 
-	outValues := callSite.Ctor.Call(inValues)
+	return r.call(callSite.Ctor, inValues, ctx)
+}
+
+// call the constructor with the typed adapter.
+func (r *CallSiteResolver) invoke(callSite *ConstructorCallSite, ctx resolverContext) (any, error) {
+	args := make([]any, len(callSite.Parameters))
+	for i, p := range callSite.Parameters {
+		v, err := r.visitCallSite(p, ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	return callSite.Ctor.invoke(args)
+}
+
+// call the constructor, the cleanup function returned by the constructor is captured
+// in the scope of the context before the instance, so it's called after the instance is disposed.
+// The cleanup of a managed instance is collected for its manager instead.
+func (r *CallSiteResolver) call(ctor *ConstructorInfo, inValues []reflect.Value, ctx resolverContext) (any, error) {
+	outValues := ctor.Call(inValues)
+
+	if ctor.ReturnsError() {
+		if errValue := outValues[len(outValues)-1]; !errValue.IsNil() {
+			return nil, errValue.Interface().(error)
+		}
+	}
+
+	if ctor.ReturnsCleanup() {
+		if cleanup := outValues[1]; !cleanup.IsNil() {
+			if ctx.cleanups != nil {
+				*ctx.cleanups = append(*ctx.cleanups, cleanup.Interface().(func()))
+			} else if err := r.captureDisposable(cleanupFunc(cleanup.Interface().(func())), ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return outValues[0].Interface(), nil
+}
+
+func (r *CallSiteResolver) visitStruct(callSite *StructCallSite, ctx resolverContext) (any, error) {
+	fieldValues := make([]any, len(callSite.Fields))
+	for i, cs := range callSite.Fields {
+		if cs == nil {
+			continue
+		}
+		v, err := r.visitCallSite(cs, ctx)
+		if err != nil {
+			return nil, err
+		}
+		fieldValues[i] = v
+	}
+
+	return callSite.Struct.New(fieldValues), nil
+}
 
-	numOut := len(outValues)
-	if numOut == 1 {
-		return outValues[0].Interface(), nil
-	} else if numOut == 2 {
-		if outValues[1].IsZero() {
-			return outValues[0].Interface(), nil
+func (r *CallSiteResolver) visitDecorator(callSite *DecoratorCallSite, ctx resolverContext) (any, error) {
+	inner, err := r.visitCallSite(callSite.Inner, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inValues := make([]reflect.Value, len(callSite.Parameters)+1)
+	inValues[0] = reflect.ValueOf(inner)
+	for i, p := range callSite.Parameters {
+		v, err := r.visitCallSite(p, ctx)
+		if err != nil {
+			return nil, err
 		}
-		if err, ok := outValues[1].Interface().(error); ok {
+		inValues[i+1] = reflect.ValueOf(v)
+	}
+
+	v, err := r.call(callSite.Decorator, inValues, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the inner instance is captured by the inner call site, or owned by the user if it's registered as an instance.
+	if callSite.Capture && !sameInstance(inner, v) {
+		if err = r.captureDisposable(v, ctx); err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("the type of the second out parameter is not error")
-	} else {
-		return nil, fmt.Errorf("unexpected output parameters")
 	}
+	return v, nil
+}
+
+// Determines if a and b are the same instance, the values of the incomparable types are never the same.
+func sameInstance(a, b any) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+func (r *CallSiteResolver) visitOptional(callSite *OptionalCallSite, ctx resolverContext) (any, error) {
+	empty := reflect.Zero(callSite.ServiceType()).Interface()
+	if callSite.Inner == nil {
+		return empty, nil
+	}
+
+	v, err := r.visitCallSite(callSite.Inner, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return empty.(optional).with(v), nil
+}
+
+func (r *CallSiteResolver) visitLazy(callSite *LazyCallSite, ctx resolverContext) (any, error) {
+	service := callSite.Service
+	l := reflect.Zero(callSite.ServiceType()).Interface().(lazy)
+
+	return l.bind(func() (any, error) {
+		return r.resolveOnDemand(service, ctx)
+	}), nil
+}
+
+// resolve the service on demand from the scope of the context that it was injected in,
+// the construction that it was injected in can't be resolved again while it's in progress.
+func (r *CallSiteResolver) resolveOnDemand(id ServiceIdentifier, ctx resolverContext) (any, error) {
+	if ctx.Scope.isDisposed() {
+		return nil, &errorx.ObjectDisposedError{Message: reflectx.TypeOf[Container]().String()}
+	}
+
+	scope, callSite, err := ctx.Scope.localCallSite(id)
+	if err != nil {
+		return nil, err
+	}
+	if callSite == nil {
+		scope = ctx.Scope
+		if callSite, err = scope.RootContainer.CallSiteFactory.GetCallSiteByIdentifier(id, newCallSiteChain()); err != nil {
+			return nil, err
+		}
+	}
+	return r.visitCallSite(callSite, resolverContext{Scope: scope, constructing: ctx.constructing})
+}
+
+func (r *CallSiteResolver) visitProvider(callSite *ProviderCallSite, ctx resolverContext) (any, error) {
+	service := callSite.Service
+
+	return newProvider(callSite.ServiceType(), func() (any, error) {
+		return r.resolveOnDemand(service, ctx)
+	}), nil
+}
+
+func (r *CallSiteResolver) visitAssisted(callSite *AssistedCallSite, ctx resolverContext) (any, error) {
+	factoryType := callSite.ServiceType()
+	// the instances created by the factory are not part of the managed instance that it's injected in.
+	ctx.cleanups = nil
+
+	return reflect.MakeFunc(factoryType, func(args []reflect.Value) []reflect.Value {
+		v, err := r.callAssisted(callSite, args, ctx)
+		return funcResults(factoryType, v, err)
+	}).Interface(), nil
+}
+
+// call the constructor of the assisted call site with the arguments of the factory,
+// the instance is captured for disposal in the scope that the factory was injected from.
+func (r *CallSiteResolver) callAssisted(callSite *AssistedCallSite, args []reflect.Value, ctx resolverContext) (any, error) {
+	if ctx.Scope.isDisposed() {
+		return nil, &errorx.ObjectDisposedError{Message: reflectx.TypeOf[Container]().String()}
+	}
+
+	inValues := make([]reflect.Value, len(callSite.Parameters))
+	for j, i := range callSite.Assisted {
+		inValues[i] = args[j]
+	}
+	for i, p := range callSite.Parameters {
+		if p == nil {
+			continue
+		}
+		v, err := r.visitCallSite(p, ctx)
+		if err != nil {
+			return nil, err
+		}
+		inValues[i] = reflect.ValueOf(v)
+	}
+
+	v, err := r.call(callSite.Ctor, inValues, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.captureDisposable(v, ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *CallSiteResolver) visitActivation(callSite *ActivationCallSite, ctx resolverContext) (any, error) {
+	v, err := r.visitCallSite(callSite.Inner, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range callSite.Activating {
+		if v, err = hook(ctx.Scope, v); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, hook := range callSite.Activated {
+		if err = hook(ctx.Scope, v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (r *CallSiteResolver) visitScopeLocal(callSite *ScopeLocalCallSite, ctx resolverContext) (any, error) {
+	scope, local, err := ctx.Scope.localCallSite(callSite.Service)
+	if err != nil {
+		return nil, err
+	}
+	if local == nil {
+		return nil, fmt.Errorf("the scope-local service '%v' is not provided by the scope", callSite.Service)
+	}
+
+	return r.visitCallSite(local, resolverContext{Scope: scope, constructing: ctx.constructing})
 }
 
 func (r *CallSiteResolver) visitRootCache(callSite CallSite, ctx resolverContext) (any, error) {
@@ -127,7 +435,10 @@ func (r *CallSiteResolver) visitRootCache(callSite CallSite, ctx resolverContext
 		return value, nil
 	}
 
-	rootScope := ctx.Scope.RootContainer.Root
+	rootScope := ctx.Scope.RootContainer.rootOf(callSite)
+	if ctx.constructing.isConstructing(callSite, rootScope) {
+		return nil, newOnDemandCircularError(callSite)
+	}
 
 	callSiteLocker := r.callSiteLockers.LoadOrCreate(callSite)
 	callSiteLocker.Lock()
@@ -137,57 +448,87 @@ func (r *CallSiteResolver) visitRootCache(callSite CallSite, ctx resolverContext
 		return value, nil
 	}
 
-	resolved, err := r.visitCallSiteMain(callSite, resolverContext{
-		Scope:         rootScope,
-		AcquiredLocks: ctx.AcquiredLocks | resolverLock_Root,
-	})
-
+	constructCtx, c := ctx.construct(callSite, rootScope)
+	resolved, err := r.visitCallSiteMain(callSite, constructCtx)
+	c.done.Store(true)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = rootScope.CaptureDisposable(resolved)
-	if err != nil {
+	if err = r.captureCallSite(callSite, resolved, resolverContext{Scope: rootScope}); err != nil {
 		return nil, err
 	}
 	callSite.SetValue(resolved)
 	return resolved, nil
 }
 
+// The scope lock is only held to access the resolved services, the construction is guarded by the lock of
+// the cache key instead, so the services resolved on demand by the constructor don't wait for the construction.
 func (r *CallSiteResolver) visitScopeCache(callSite CallSite, ctx resolverContext) (any, error) {
 	scope := ctx.Scope
 	if scope.IsRootScope {
 		return r.visitRootCache(callSite, ctx)
 	}
 
-	resolvedServices := scope.ResolvedServices
+	if callSite.Cache().ScopeMode == ScopeMode_Outermost {
+		scope = scope.Outermost()
+	}
+
 	cacheKey := callSite.Cache().Key
+	if resolved, ok := scope.resolved(cacheKey); ok {
+		return resolved, nil
+	}
 
-	if (ctx.AcquiredLocks & resolverLock_Scope) == 0 {
-		scope.Locker.Lock()
-		defer scope.Locker.Unlock()
+	// the instances resolved by the parent scopes are shared with the nested scopes.
+	if resolved, ok := scope.parentResolved(cacheKey); ok {
+		return resolved, nil
+	}
+
+	if ctx.constructing.isConstructing(callSite, scope) {
+		return nil, newOnDemandCircularError(callSite)
 	}
 
-	if resolved, ok := resolvedServices[cacheKey]; ok {
+	keyLocker := scope.keyLockers.LoadOrCreate(cacheKey)
+	keyLocker.Lock()
+	defer keyLocker.Unlock()
+
+	if resolved, ok := scope.resolved(cacheKey); ok {
 		return resolved, nil
 	}
 
-	resolved, err := r.visitCallSiteMain(callSite, resolverContext{
-		Scope:         scope,
-		AcquiredLocks: ctx.AcquiredLocks | resolverLock_Scope,
-	})
+	constructCtx, c := ctx.construct(callSite, scope)
+	resolved, err := r.visitCallSiteMain(callSite, constructCtx)
+	c.done.Store(true)
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err = scope.CaptureDisposableWithoutLock(resolved); err != nil {
+	if err = r.captureCallSite(callSite, resolved, resolverContext{Scope: scope}); err != nil {
 		return nil, err
 	}
 
-	resolvedServices[cacheKey] = resolved
+	scope.Locker.Lock()
+	scope.ResolvedServices[cacheKey] = resolved
+	scope.Locker.Unlock()
 	return resolved, nil
 }
 
+// the error of a service resolved on demand while it's being constructed.
+func newOnDemandCircularError(callSite CallSite) error {
+	return &errorx.CircularDependencyError{
+		Message: fmt.Sprintf("the service '%v' is resolved on demand while it's being constructed", callSite.ServiceType()),
+	}
+}
+
+func (r *CallSiteResolver) visitManagedCache(callSite CallSite, ctx resolverContext) (any, error) {
+	cache := callSite.Cache()
+	if ctx.Scope.RootContainer.isSharedSingleton(callSite) {
+		// the managed singleton shared with the child container is resolved from the container that owns it.
+		ctx = resolverContext{Scope: ctx.Scope.RootContainer.rootOf(callSite), constructing: ctx.constructing}
+	}
+	return cache.Manager.Resolve(ctx.Scope, cache.Key, &activator{resolver: r, callSite: callSite, ctx: ctx})
+}
+
 func (r *CallSiteResolver) visitConstant(callSite *ConstantCallSite, ctx resolverContext) (any, error) {
 	return callSite.DefaultValue(), nil
 }
@@ -216,5 +557,6 @@ func (r *CallSiteResolver) visitSlice(callSite *SliceCallSite, ctx resolverConte
 func newCallSiteResolver() *CallSiteResolver {
 	return &CallSiteResolver{
 		callSiteLockers: &syncx.LockMap{},
+		managedCleanups: syncx.NewMap[any, cleanupFunc](),
 	}
 }
@@ -1,12 +1,15 @@
 package di
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"sync"
 
 	"github.com/dozm/di/errorx"
 	"github.com/dozm/di/reflectx"
+	"github.com/dozm/di/syncx"
 )
 
 //
@@ -15,38 +18,222 @@ type ContainerEngineScope struct {
 	IsRootScope      bool
 	ResolvedServices map[ServiceCacheKey]any
 	Locker           *sync.Mutex
-	disposed         bool
-	disposables      []Disposable
+	// the parent scope of a nested scope, nil if the scope is not nested.
+	Parent      *ContainerEngineScope
+	disposed    bool
+	disposables []any
+	// the live nested scopes.
+	children map[*ContainerEngineScope]struct{}
+	// the factory of the services registered by CreateScopeWith, nil if there is none.
+	locals *CallSiteFactory
+	// the locks that guard the construction of the scoped services by their cache keys.
+	keyLockers syncx.LockMap
 }
 
 func (s *ContainerEngineScope) Get(serviceType reflect.Type) (any, error) {
+	return s.GetKeyed(serviceType, nil)
+}
+
+func (s *ContainerEngineScope) GetKeyed(serviceType reflect.Type, key any) (any, error) {
 	if s.disposed {
 		return nil, &errorx.ObjectDisposedError{Message: reflectx.TypeOf[Container]().String()}
 	}
 
-	return s.RootContainer.GetWithScope(serviceType, s)
+	id := newServiceIdentifier(serviceType, key)
+	scope, local, err := s.localCallSite(id)
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		return CallSiteResolverInstance.Resolve(local, scope)
+	}
+
+	return s.RootContainer.GetWithScope(id, s)
+}
+
+// the call site of the service registered by CreateScopeWith in the nearest scope, nil if there is none.
+func (s *ContainerEngineScope) localCallSite(id ServiceIdentifier) (*ContainerEngineScope, CallSite, error) {
+	for scope := s; scope != nil; scope = scope.Parent {
+		if scope.locals == nil {
+			continue
+		}
+		if scope.locals.hasDescriptor(id) {
+			callSite, err := scope.locals.GetCallSiteByIdentifier(id, newCallSiteChain())
+			return scope, callSite, err
+		}
+	}
+	return nil, nil, nil
 }
 
 func (s *ContainerEngineScope) Container() Container {
 	return s
 }
 
+// Create a scope nested in the scope s, the nested scope shares the scoped services resolved by s,
+// it creates a scope that is not nested if s is the root scope.
 func (s *ContainerEngineScope) CreateScope() Scope {
-	return s.RootContainer.CreateScope()
+	return s.CreateScopeWith(nil)
+}
+
+// Create a scope nested in the scope s with the services registered by configure,
+// the services are only visible inside the scope and its nested scopes.
+// It creates a scope that is not nested if s is the root scope.
+func (s *ContainerEngineScope) CreateScopeWith(configure func(ScopeBuilder)) Scope {
+	if s.IsRootScope {
+		return s.RootContainer.CreateScopeWith(configure)
+	}
+
+	child := newEngineScope(s.RootContainer, false)
+	child.locals = newScopeLocalFactory(s.RootContainer.CallSiteFactory, configure)
+	child.Parent = s
+
+	s.Locker.Lock()
+	defer s.Locker.Unlock()
+	if s.disposed {
+		panic(&errorx.ObjectDisposedError{Message: reflectx.TypeOf[Scope]().String()})
+	}
+	if s.children == nil {
+		s.children = make(map[*ContainerEngineScope]struct{})
+	}
+	s.children[child] = struct{}{}
+	return child
+}
+
+// The root scope of the container that created the scope.
+func (s *ContainerEngineScope) Root() *ContainerEngineScope {
+	return s.RootContainer.Root
+}
+
+// The root scope of the container as a LifetimeScope.
+func (s *ContainerEngineScope) RootScope() LifetimeScope {
+	return s.Root()
+}
+
+// The outermost scope of the nested scopes that s is in.
+func (s *ContainerEngineScope) Outermost() *ContainerEngineScope {
+	for s.Parent != nil {
+		s = s.Parent
+	}
+	return s
+}
+
+// the instance of the service resolved by the scope.
+func (s *ContainerEngineScope) resolved(key ServiceCacheKey) (any, bool) {
+	s.Locker.Lock()
+	defer s.Locker.Unlock()
+	resolved, ok := s.ResolvedServices[key]
+	return resolved, ok
+}
+
+func (s *ContainerEngineScope) isDisposed() bool {
+	s.Locker.Lock()
+	defer s.Locker.Unlock()
+	return s.disposed
+}
+
+// the instance of the service resolved by the nearest parent scope.
+func (s *ContainerEngineScope) parentResolved(key ServiceCacheKey) (any, bool) {
+	for p := s.Parent; p != nil; p = p.Parent {
+		p.Locker.Lock()
+		resolved, ok := p.ResolvedServices[key]
+		p.Locker.Unlock()
+		if ok {
+			return resolved, true
+		}
+	}
+	return nil, false
+}
+
+// take the live nested scopes of the disposed scope.
+func (s *ContainerEngineScope) takeChildren() []*ContainerEngineScope {
+	s.Locker.Lock()
+	defer s.Locker.Unlock()
+	children := make([]*ContainerEngineScope, 0, len(s.children))
+	for child := range s.children {
+		children = append(children, child)
+	}
+	s.children = nil
+	return children
+}
+
+func (s *ContainerEngineScope) removeChild(child *ContainerEngineScope) {
+	s.Locker.Lock()
+	defer s.Locker.Unlock()
+	delete(s.children, child)
+}
+
+// Dispose the captured services in the reverse order of creation,
+// it returns an AggregateError of the errors annotated by the service types.
+func (s *ContainerEngineScope) Dispose() error {
+	return s.DisposeContext(context.Background())
 }
 
-func (s *ContainerEngineScope) Dispose() {
+// Dispose the captured services in the reverse order of creation with the context,
+// it stops waiting when the context is done and reports the services that didn't finish.
+// The live nested scopes are disposed first.
+func (s *ContainerEngineScope) DisposeContext(ctx context.Context) error {
 	disposables := s.BeginDispose()
-	for i := len(disposables) - 1; i >= 0; i-- {
-		disposables[i].Dispose()
+	errs := &errorx.AggregateError{}
+
+	for _, child := range s.takeChildren() {
+		if err := child.DisposeContext(ctx); err != nil {
+			errs.Add(err)
+		}
 	}
+
+	// the services are disposed without waiting if the context is never done.
+	if ctx.Done() == nil {
+		for i := len(disposables) - 1; i >= 0; i-- {
+			if err := disposeContext(ctx, disposables[i]); err != nil {
+				errs.Add(err)
+			}
+		}
+		return errs.OrNil()
+	}
+
+	var mu sync.Mutex
+	// the number of the services not disposed yet.
+	remaining := len(disposables)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := len(disposables) - 1; i >= 0 && ctx.Err() == nil; i-- {
+			err := disposeContext(ctx, disposables[i])
+
+			mu.Lock()
+			if err != nil {
+				errs.Add(err)
+			}
+			remaining = i
+			mu.Unlock()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	// the errors are copied, as the disposal may still be running.
+	mu.Lock()
+	defer mu.Unlock()
+	result := &errorx.AggregateError{Errors: append([]error(nil), errs.Errors...)}
+	if remaining > 0 {
+		serviceTypes := make([]reflect.Type, remaining)
+		for i := range serviceTypes {
+			serviceTypes[i] = reflect.TypeOf(disposables[remaining-i-1])
+		}
+		result.Add(&errorx.DisposeCanceledError{ServiceTypes: serviceTypes, Err: ctx.Err()})
+	}
+	return result.OrNil()
 }
 
-func (s *ContainerEngineScope) Disposables() []Disposable {
+// the services captured to be disposed.
+func (s *ContainerEngineScope) Disposables() []any {
 	return s.disposables
 }
 
-func (s *ContainerEngineScope) BeginDispose() []Disposable {
+func (s *ContainerEngineScope) BeginDispose() []any {
 	s.Locker.Lock()
 	if s.disposed {
 		s.Locker.Unlock()
@@ -55,50 +242,99 @@ func (s *ContainerEngineScope) BeginDispose() []Disposable {
 	s.disposed = true
 	s.Locker.Unlock()
 
-	if s.IsRootScope && !s.RootContainer.IsDisposed() {
-		s.RootContainer.Dispose()
+	if s.IsRootScope {
+		s.RootContainer.disposed = true
+	}
+	if s.Parent != nil {
+		s.Parent.removeChild(s)
 	}
 
 	return s.disposables
 }
 
-func (s *ContainerEngineScope) CaptureDisposable(service any) (Disposable, error) {
-	d, ok := service.(Disposable)
-	if service == s || !ok {
-		return d, nil
+// Capture the service to be disposed with the scope if it's disposable,
+// the service is disposed immediately if the scope has been disposed.
+func (s *ContainerEngineScope) CaptureDisposable(service any) error {
+	if service == s || !isDisposable(service) {
+		return nil
 	}
 
-	disposed := false
 	s.Locker.Lock()
-	if s.disposed {
-		disposed = true
-	} else {
-		s.disposables = append(s.disposables, d)
+	disposed := s.disposed
+	if !disposed {
+		s.disposables = append(s.disposables, service)
 	}
 	s.Locker.Unlock()
 
 	if disposed {
-		d.Dispose()
-		return d, fmt.Errorf("capture disposable service '%v', scope disposed", reflect.TypeOf(service))
+		dispose(service)
+		return fmt.Errorf("capture disposable service '%v', scope disposed", reflect.TypeOf(service))
 	}
+	return nil
+}
 
-	return d, nil
+func (s *ContainerEngineScope) CaptureDisposableWithoutLock(service any) error {
+	if service == s || !isDisposable(service) {
+		return nil
+	}
 
+	if s.disposed {
+		dispose(service)
+		return fmt.Errorf("capture disposable service '%v', scope disposed", reflect.TypeOf(service))
+	}
+
+	s.disposables = append(s.disposables, service)
+	return nil
 }
 
-func (s *ContainerEngineScope) CaptureDisposableWithoutLock(service any) (Disposable, error) {
-	d, ok := service.(Disposable)
-	if service == s || !ok {
-		return d, nil
+// Determines if the service implements Disposable, DisposableWithError, DisposableContext or io.Closer.
+func isDisposable(service any) bool {
+	switch service.(type) {
+	case Disposable, DisposableWithError, DisposableContext, io.Closer:
+		return true
+	default:
+		return false
 	}
+}
 
-	if s.disposed {
+func dispose(service any) error {
+	return disposeContext(context.Background(), service)
+}
+
+// dispose the service with the context, the error is annotated by the service type.
+func disposeContext(ctx context.Context, service any) (err error) {
+	switch d := service.(type) {
+	case managedCleanup:
+		// the errors are annotated by the services that the cleanup disposes.
+		return d()
+	case DisposableContext:
+		err = d.Dispose(ctx)
+	case DisposableWithError:
+		err = d.Dispose()
+	case Disposable:
 		d.Dispose()
-		return d, fmt.Errorf("capture disposable service '%v', scope disposed", reflect.TypeOf(service))
-	} else {
-		s.disposables = append(s.disposables, d)
-		return d, nil
+	case io.Closer:
+		err = d.Close()
+	}
+
+	if err != nil {
+		err = &errorx.DisposeError{ServiceType: reflect.TypeOf(service), Err: err}
 	}
+	return
+}
+
+// The cleanup function returned by a constructor, it's captured as a disposable by the owning scope.
+type cleanupFunc func()
+
+func (f cleanupFunc) Dispose() {
+	f()
+}
+
+// The cleanup function registered by a LifetimeManager, its error is reported as it is.
+type managedCleanup func() error
+
+func (f managedCleanup) Dispose() error {
+	return f()
 }
 
 func newEngineScope(c *container, isRootScope bool) *ContainerEngineScope {
@@ -107,6 +343,6 @@ func newEngineScope(c *container, isRootScope bool) *ContainerEngineScope {
 		IsRootScope:      isRootScope,
 		ResolvedServices: make(map[ServiceCacheKey]any),
 		Locker:           new(sync.Mutex),
-		disposables:      make([]Disposable, 0),
+		disposables:      make([]any, 0),
 	}
 }
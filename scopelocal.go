@@ -0,0 +1,74 @@
+package di
+
+import (
+	"fmt"
+
+	"github.com/dozm/di/reflectx"
+)
+
+// ScopeBuilder registers the services only visible inside a scope,
+// the singleton descriptors are treated as scoped.
+// A service registered in the container can only be provided by the scope if it's registered by ScopeLocal,
+// so the services registered in the container that depend on it are resolved to the instance of the scope.
+//
+//	scope := scopeFactory.CreateScopeWith(func(sb di.ScopeBuilder) {
+//		sb.Add(di.Instance[*http.Request](req))
+//	})
+type ScopeBuilder interface {
+	Add(...*Descriptor)
+}
+
+type scopeBuilder struct {
+	descriptors []*Descriptor
+}
+
+func (b *scopeBuilder) Add(d ...*Descriptor) {
+	for _, descriptor := range d {
+		if descriptor.Lifetime == Lifetime_Singleton && descriptor.Instance == nil {
+			clone := *descriptor
+			clone.Lifetime = Lifetime_Scoped
+			descriptor = &clone
+		}
+		b.descriptors = append(b.descriptors, withForwards(descriptor)...)
+	}
+}
+
+// Create the factory of the services registered by configure,
+// the dependencies not registered by configure are resolved from the parent factory.
+// It returns nil if configure registers nothing, and panics if configure overrides a service
+// registered in the parent factory that is not registered by ScopeLocal.
+func newScopeLocalFactory(parent *CallSiteFactory, configure func(ScopeBuilder)) *CallSiteFactory {
+	if configure == nil {
+		return nil
+	}
+
+	b := &scopeBuilder{}
+	configure(b)
+	if len(b.descriptors) == 0 {
+		return nil
+	}
+
+	f := newCallSiteFactory(b.descriptors)
+	for id := range f.descriptorLookup {
+		if cacheItem, ok := parent.descriptorLookup[id]; ok && !cacheItem.Last().ScopeLocal {
+			panic(fmt.Errorf("the service '%v' registered in the container can't be provided by the scope, register it by ScopeLocal", id))
+		}
+	}
+	f.parent = parent
+	return f
+}
+
+// New a descriptor of the service T provided by the scopes created with CreateScopeWith,
+// so the services registered in the container can depend on it.
+func ScopeLocal[T any]() *Descriptor {
+	return &Descriptor{
+		ServiceType: reflectx.TypeOf[T](),
+		Lifetime:    Lifetime_Scoped,
+		ScopeLocal:  true,
+	}
+}
+
+// Add a descriptor of the service T provided by the scopes created with CreateScopeWith to the ContainerBuilder.
+func AddScopeLocal[T any](cb ContainerBuilder) {
+	cb.Add(ScopeLocal[T]())
+}
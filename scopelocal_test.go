@@ -0,0 +1,131 @@
+package di
+
+import (
+	"testing"
+)
+
+type scopeLocalRequest struct{ Path string }
+type scopeLocalHandler struct{ Request *scopeLocalRequest }
+type scopeLocalLogger struct{}
+type scopeLocalAudit struct {
+	Request *scopeLocalRequest
+	Logger  *scopeLocalLogger
+}
+
+func TestScopeLocal(t *testing.T) {
+	b := Builder()
+	AddScopeLocal[*scopeLocalRequest](b)
+	AddScoped[*scopeLocalHandler](b, func(r *scopeLocalRequest) *scopeLocalHandler {
+		return &scopeLocalHandler{Request: r}
+	})
+	AddSingleton[*scopeLocalLogger](b, func() *scopeLocalLogger { return &scopeLocalLogger{} })
+
+	c := b.Build()
+	req := &scopeLocalRequest{Path: "/"}
+	scope := Get[ScopeFactory](c).CreateScopeWith(func(sb ScopeBuilder) {
+		sb.Add(Instance[*scopeLocalRequest](req))
+		sb.Add(Singleton[*scopeLocalAudit](func(r *scopeLocalRequest, l *scopeLocalLogger) *scopeLocalAudit {
+			return &scopeLocalAudit{Request: r, Logger: l}
+		}))
+	})
+
+	if Get[*scopeLocalRequest](scope.Container()) != req {
+		t.Error("expect the instance provided by the scope")
+	}
+	if Get[*scopeLocalHandler](scope.Container()).Request != req {
+		t.Error("expect the container service depend on the scope-local service")
+	}
+
+	audit := Get[*scopeLocalAudit](scope.Container())
+	if audit.Request != req || audit.Logger != Get[*scopeLocalLogger](c) {
+		t.Error("expect the scope service depend on the container services")
+	}
+	if Get[*scopeLocalAudit](scope.Container()) != audit {
+		t.Error("expect the scope service resolved once in the scope")
+	}
+
+	nested := scope.CreateScope()
+	if Get[*scopeLocalHandler](nested.Container()).Request != req || Get[*scopeLocalAudit](nested.Container()) == nil {
+		t.Error("expect the scope-local services visible in the nested scope")
+	}
+
+	other := &scopeLocalRequest{Path: "/other"}
+	shadow := scope.CreateScopeWith(func(sb ScopeBuilder) {
+		sb.Add(Instance[*scopeLocalRequest](other))
+	})
+	if Get[*scopeLocalRequest](shadow.Container()) != other {
+		t.Error("expect the instance provided by the nearest scope")
+	}
+
+	if _, err := TryGet[*scopeLocalAudit](Get[ScopeFactory](c).CreateScope().Container()); err == nil {
+		t.Error("expect the scope service invisible outside the scope")
+	}
+	if _, err := TryGet[*scopeLocalHandler](Get[ScopeFactory](c).CreateScope().Container()); err == nil {
+		t.Error("expect error if the scope doesn't provide the service")
+	}
+}
+
+func TestScopeLocal_Validate(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = true
+	})
+	AddScopeLocal[*scopeLocalRequest](b)
+
+	c := b.Build()
+	if _, err := TryGet[*scopeLocalRequest](c); err == nil {
+		t.Error("expect error if resolved from the root scope")
+	}
+
+	b = Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = true
+		o.ValidateOnBuild = true
+	})
+	AddScopeLocal[*scopeLocalRequest](b)
+	AddSingleton[*scopeLocalHandler](b, func(r *scopeLocalRequest) *scopeLocalHandler {
+		return &scopeLocalHandler{Request: r}
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expect panic if a singleton depends on a scope-local service")
+		}
+	}()
+	b.Build()
+}
+
+func TestScopeLocal_Nested(t *testing.T) {
+	c := Builder().Build()
+	outer := Get[ScopeFactory](c).CreateScopeWith(func(sb ScopeBuilder) {
+		sb.Add(Scoped[*scopeLocalRequest](func() *scopeLocalRequest { return &scopeLocalRequest{Path: "outer"} }))
+	})
+	if Get[*scopeLocalRequest](outer.Container()).Path != "outer" {
+		t.Error("assertion failed")
+	}
+
+	inner := outer.CreateScopeWith(func(sb ScopeBuilder) {
+		sb.Add(Scoped[*scopeLocalRequest](func() *scopeLocalRequest { return &scopeLocalRequest{Path: "inner"} }))
+	})
+	if Get[*scopeLocalRequest](inner.Container()).Path != "inner" {
+		t.Error("expect the service registered by the nested scope")
+	}
+	if Get[*scopeLocalRequest](outer.CreateScope().Container()).Path != "outer" {
+		t.Error("expect the instance of the outer scope shared with its nested scopes")
+	}
+}
+
+func TestScopeLocal_OverrideRegistered(t *testing.T) {
+	b := Builder()
+	AddScoped[*scopeLocalRequest](b, func() *scopeLocalRequest { return &scopeLocalRequest{Path: "container"} })
+
+	c := b.Build()
+	defer func() {
+		if recover() == nil {
+			t.Error("expect panic if the scope overrides a service registered in the container")
+		}
+	}()
+	Get[ScopeFactory](c).CreateScopeWith(func(sb ScopeBuilder) {
+		sb.Add(Instance[*scopeLocalRequest](&scopeLocalRequest{Path: "override"}))
+	})
+}
@@ -0,0 +1,340 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dozm/di/errorx"
+	"github.com/dozm/di/reflectx"
+)
+
+// The tag name of the struct fields to be injected.
+const InjectTag = "di"
+
+// In is a marker to be embedded in a parameter object of a constructor,
+// all of the exported fields of the parameter object are resolved individually.
+//
+//	type HandlerParams struct {
+//		di.In
+//		DB     *sql.DB `di:"key=primary"`
+//		Cache  Cache   `di:"optional"`
+//		Routes []Route `di:"group=api"`
+//	}
+type In struct{}
+
+var InType = reflectx.TypeOf[In]()
+
+// Out is a marker to be embedded in a result object returned by a constructor,
+// each of the exported fields of the result object is registered as a service
+// with the lifetime of the result object, and shares the constructor invocation,
+// except that a transient result object is constructed each time one of its fields is resolved.
+//
+//	type ClientResult struct {
+//		di.Out
+//		Client  *Client
+//		Metrics *Metrics
+//		Health  HealthCheck `di:"group=health"`
+//	}
+type Out struct{}
+
+var OutType = reflectx.TypeOf[Out]()
+
+// A field of the struct to be injected.
+type StructField struct {
+	Name  string
+	Index []int
+	Type  reflect.Type
+	// service key of the field, nil if the field is not keyed.
+	Key any
+	// the field is left as zero value if the service is not registered.
+	Optional bool
+}
+
+func (f *StructField) Identifier() ServiceIdentifier {
+	return newServiceIdentifier(f.Type, f.Key)
+}
+
+type StructInfo struct {
+	// the struct type or the pointer to struct type.
+	Type   reflect.Type
+	Fields []StructField
+}
+
+func (s *StructInfo) IsPointer() bool {
+	return s.Type.Kind() == reflect.Pointer
+}
+
+func (s *StructInfo) StructType() reflect.Type {
+	if s.IsPointer() {
+		return s.Type.Elem()
+	}
+	return s.Type
+}
+
+// Create a new value of the struct with the field values, the nil field value is skipped.
+func (s *StructInfo) New(fieldValues []any) any {
+	v := reflect.New(s.StructType())
+	s.set(v.Elem(), fieldValues)
+
+	if s.IsPointer() {
+		return v.Interface()
+	}
+	return v.Elem().Interface()
+}
+
+func (s *StructInfo) set(v reflect.Value, fieldValues []any) {
+	for i, f := range s.Fields {
+		if fv := fieldValues[i]; fv != nil {
+			v.FieldByIndex(f.Index).Set(reflect.ValueOf(fv))
+		}
+	}
+}
+
+func newStructInfo(t reflect.Type) (*StructInfo, error) {
+	st := t
+	if st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("the type '%v' is neither a struct nor a pointer to struct", t)
+	}
+
+	return newStructInfoWithFields(t, st, false)
+}
+
+// Determines if the type is a parameter object that embeds In.
+func isParamObject(t reflect.Type) bool {
+	return embedsMarker(t, InType)
+}
+
+// Determines if the type is a result object that embeds Out.
+func isResultObject(t reflect.Type) bool {
+	return embedsMarker(t, OutType)
+}
+
+func embedsMarker(t reflect.Type, marker reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.Anonymous && sf.Type == marker {
+			return true
+		}
+	}
+	return false
+}
+
+func newParamObjectInfo(t reflect.Type) (*StructInfo, error) {
+	return newStructInfoWithFields(t, t, true)
+}
+
+// all of the fields except the embedded marker are injected if allFields is true,
+// otherwise only the fields tagged with `di` are injected.
+func newStructInfoWithFields(t reflect.Type, st reflect.Type, allFields bool) (*StructInfo, error) {
+	info := &StructInfo{Type: t}
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		if sf.Anonymous && sf.Type == InType {
+			continue
+		}
+
+		tag, ok := sf.Tag.Lookup(InjectTag)
+		if !ok && !allFields {
+			continue
+		}
+
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("the field '%v' of the struct '%v' to be injected is not exported", sf.Name, st)
+		}
+
+		field := StructField{Name: sf.Name, Index: sf.Index, Type: sf.Type}
+		if err := parseInjectTag(tag, &field); err != nil {
+			return nil, fmt.Errorf("invalid tag of the field '%v' of the struct '%v': %w", sf.Name, st, err)
+		}
+		info.Fields = append(info.Fields, field)
+	}
+
+	return info, nil
+}
+
+// parse the tag like `di:"optional,key=primary"`.
+// the group option resolves a slice field with all of the element services keyed by the group name.
+func parseInjectTag(tag string, field *StructField) error {
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		name, value, hasValue := strings.Cut(opt, "=")
+		switch {
+		case opt == "":
+		case name == "optional" && !hasValue:
+			field.Optional = true
+		case (name == "key" || name == "group") && hasValue && value != "":
+			if field.Key != nil {
+				return errors.New("the options key and group are exclusive")
+			}
+			if name == "group" && field.Type.Kind() != reflect.Slice {
+				return fmt.Errorf("the type '%v' of a group is not a slice", field.Type)
+			}
+			field.Key = value
+		default:
+			return fmt.Errorf("unknown option '%v'", opt)
+		}
+	}
+	return nil
+}
+
+// parse the tag of a field of the result object like `di:"key=primary"`.
+// the key and group options are equivalent, the field is registered as a service keyed by the value.
+func parseResultTag(tag string, field *StructField) error {
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		name, value, hasValue := strings.Cut(opt, "=")
+		switch {
+		case opt == "":
+		case (name == "key" || name == "group") && hasValue && value != "":
+			if field.Key != nil {
+				return errors.New("the options key and group are exclusive")
+			}
+			field.Key = value
+		default:
+			return fmt.Errorf("unknown option '%v'", opt)
+		}
+	}
+	return nil
+}
+
+// The private key of a result object registration, so the fields are resolved from their own registration
+// even if the result object type is registered multiple times.
+type resultObjectKey struct {
+	descriptor *Descriptor
+}
+
+// Create the descriptors of the fields of the result object that the descriptor d provides,
+// the first one forwards the private key of the registration to d.
+func newResultFieldDescriptors(d *Descriptor) ([]*Descriptor, error) {
+	t := d.ServiceType
+	key := &resultObjectKey{descriptor: d}
+	descriptors := make([]*Descriptor, 0, t.NumField()+1)
+	descriptors = append(descriptors, &Descriptor{
+		ServiceType: t,
+		ServiceKey:  key,
+		Lifetime:    d.Lifetime,
+		Forward:     d,
+		Module:      d.Module,
+	})
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type == OutType {
+			continue
+		}
+
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("the field '%v' of the result object '%v' is not exported", sf.Name, t)
+		}
+
+		field := StructField{Name: sf.Name, Index: sf.Index, Type: sf.Type}
+		if err := parseResultTag(sf.Tag.Get(InjectTag), &field); err != nil {
+			return nil, fmt.Errorf("invalid tag of the field '%v' of the result object '%v': %w", sf.Name, t, err)
+		}
+
+		index := sf.Index
+		getter := reflect.MakeFunc(
+			reflect.FuncOf([]reflect.Type{t}, []reflect.Type{sf.Type}, false),
+			func(in []reflect.Value) []reflect.Value {
+				return []reflect.Value{in[0].FieldByIndex(index)}
+			})
+
+		ci, err := newConstructorInfo(getter.Interface())
+		if err != nil {
+			return nil, err
+		}
+		ci.InKeys = []any{key}
+
+		descriptors = append(descriptors, &Descriptor{
+			ServiceType: sf.Type,
+			ServiceKey:  field.Key,
+			Lifetime:    d.Lifetime,
+			Ctor:        ci,
+			Module:      d.Module,
+		})
+	}
+
+	return descriptors, nil
+}
+
+// Expand the result objects, the descriptors of the fields follow the descriptor of the result object.
+func expandResultObjects(descriptors []*Descriptor) ([]*Descriptor, error) {
+	result := make([]*Descriptor, 0, len(descriptors))
+	for _, d := range descriptors {
+		result = append(result, d)
+		if !isResultObject(d.ServiceType) {
+			continue
+		}
+
+		fields, err := newResultFieldDescriptors(d)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fields...)
+	}
+	return result, nil
+}
+
+func isServiceNotFound(err error, id ServiceIdentifier) bool {
+	var notFound *errorx.ServiceNotFound
+	return errors.As(err, &notFound) &&
+		notFound.ServiceType == id.ServiceType &&
+		notFound.ServiceKey == id.ServiceKey
+}
+
+// Inject the services into the fields tagged with `di` of the struct that the target points to.
+func Inject(c Container, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errorx.NewArgumentError("target must be a non-nil pointer to struct")
+	}
+
+	info, err := newStructInfo(v.Type())
+	if err != nil {
+		return err
+	}
+
+	fieldValues, err := resolveFields(c, info)
+	if err != nil {
+		return err
+	}
+
+	info.set(v.Elem(), fieldValues)
+	return nil
+}
+
+func resolveFields(c Container, info *StructInfo) ([]any, error) {
+	fieldValues := make([]any, len(info.Fields))
+	for i, f := range info.Fields {
+		fv, err := getKeyed(c, f.Type, f.Key)
+		if err != nil {
+			if f.Optional && isServiceNotFound(err, f.Identifier()) {
+				continue
+			}
+			return nil, err
+		}
+		fieldValues[i] = fv
+	}
+	return fieldValues, nil
+}
+
+// New a struct descriptor, T is a struct or a pointer to struct
+// whose fields tagged with `di` are injected.
+func Struct[T any](lifetime Lifetime, opts ...DescriptorOption) *Descriptor {
+	return NewStructDescriptor(reflectx.TypeOf[T](), lifetime).apply(opts)
+}
+
+// Add a struct service descriptor to the ContainerBuilder.
+// T is a struct or a pointer to struct whose fields tagged with `di` are injected,
+// cb is the ContainerBuilder,
+// lifetime is the lifetime of the service T.
+func AddStruct[T any](cb ContainerBuilder, lifetime Lifetime, opts ...DescriptorOption) {
+	cb.Add(Struct[T](lifetime, opts...))
+}
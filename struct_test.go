@@ -0,0 +1,212 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/dozm/di/errorx"
+	"github.com/dozm/di/reflectx"
+)
+
+type injectedHandler struct {
+	Name     string            `di:""`
+	Count    int               `di:"optional"`
+	Primary  *DisposableStruct `di:"key=primary"`
+	Replicas []*DisposableStruct
+	Scoped   *int32 `di:"optional"`
+}
+
+func TestStruct_AddStruct(t *testing.T) {
+	b := Builder()
+	AddInstance[string](b, "handler")
+	AddKeyedSingleton[*DisposableStruct](b, "primary", func() *DisposableStruct { return &DisposableStruct{Value: 1} })
+	AddStruct[*injectedHandler](b, Lifetime_Transient)
+
+	c := b.Build()
+
+	h := Get[*injectedHandler](c)
+	if h.Name != "handler" || h.Count != 0 || h.Primary.Value != 1 || h.Replicas != nil || h.Scoped != nil {
+		t.Error("assertion failed")
+	}
+
+	if h == Get[*injectedHandler](c) {
+		t.Error("expect a new instance for the transient service")
+	}
+}
+
+func TestStruct_ValidateScopes(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateScopes = true
+	})
+	AddInstance[string](b, "handler")
+	AddKeyedSingleton[*DisposableStruct](b, "primary", func() *DisposableStruct { return &DisposableStruct{} })
+	AddScoped[*int32](b, func() *int32 { return new(int32) })
+	AddStruct[injectedHandler](b, Lifetime_Singleton)
+
+	c := b.Build()
+
+	if _, err := TryGet[injectedHandler](c); err == nil {
+		t.Error("expect an error when a singleton consumes a scoped service")
+	}
+}
+
+func TestStruct_CircularDependency(t *testing.T) {
+	type node struct {
+		Next *injectedHandler `di:""`
+	}
+
+	b := Builder()
+	AddStruct[*node](b, Lifetime_Transient)
+	AddTransient[*injectedHandler](b, func(n *node) *injectedHandler { return nil })
+
+	_, err := TryGet[*node](b.Build())
+	if _, ok := err.(*errorx.CircularDependencyError); !ok {
+		t.Error("assertion failed")
+	}
+}
+
+func TestInject(t *testing.T) {
+	b := Builder()
+	AddInstance[string](b, "handler")
+	c := b.Build()
+
+	var h injectedHandler
+	if err := Inject(c, &h); err == nil {
+		t.Error("expect an error for the missing keyed service")
+	}
+
+	var target struct {
+		Name  string `di:""`
+		Count int    `di:"optional"`
+	}
+	if err := Inject(c, &target); err != nil || target.Name != "handler" {
+		t.Error("assertion failed")
+	}
+
+	if err := Inject(c, target); err == nil {
+		t.Error("expect an error for the non-pointer target")
+	}
+}
+
+type handlerParams struct {
+	In
+	Name    string
+	Count   int               `di:"optional"`
+	Primary *DisposableStruct `di:"key=primary"`
+	Routes  []string          `di:"group=api"`
+}
+
+func TestStruct_ParamObject(t *testing.T) {
+	b := Builder()
+	AddInstance[string](b, "handler")
+	AddKeyedSingleton[*DisposableStruct](b, "primary", func() *DisposableStruct { return &DisposableStruct{Value: 1} })
+	AddKeyedInstance[string](b, "api", "/a")
+	AddKeyedInstance[string](b, "api", "/b")
+	AddTransient[*injectedHandler](b, func(p handlerParams) *injectedHandler {
+		return &injectedHandler{Name: p.Name, Count: p.Count, Primary: p.Primary, Replicas: []*DisposableStruct{p.Primary}}
+	})
+	AddTransient[int16](b, func(p handlerParams, n int8) int16 { return int16(len(p.Routes)) })
+
+	c := b.Build()
+
+	h := Get[*injectedHandler](c)
+	if h.Name != "handler" || h.Count != 0 || h.Primary.Value != 1 {
+		t.Error("assertion failed")
+	}
+
+	_, err := TryGet[int16](c)
+	if e, ok := err.(*errorx.ServiceNotFound); !ok || e.ServiceType != reflectx.TypeOf[int8]() {
+		t.Error("assertion failed")
+	}
+
+	results, err := Invoke(c, func(p handlerParams) []string { return p.Routes })
+	if err != nil || len(results[0].([]string)) != 2 {
+		t.Error("assertion failed")
+	}
+}
+
+func TestStruct_ParamObjectDependency(t *testing.T) {
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.ValidateOnBuild = true
+	})
+	AddTransient[int16](b, func(p handlerParams) int16 { return 0 })
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expect a validation error for the field of the parameter object")
+		}
+	}()
+
+	b.Build()
+}
+
+type clientResult struct {
+	Out
+	Client  *DisposableStruct
+	Name    string
+	Healthy bool `di:"group=health"`
+}
+
+func TestStruct_ResultObject(t *testing.T) {
+	calls := 0
+	b := Builder()
+	AddScoped[clientResult](b, func() (clientResult, error) {
+		calls++
+		return clientResult{Client: &DisposableStruct{Value: calls}, Name: "client", Healthy: true}, nil
+	})
+	AddKeyedInstance[bool](b, "health", false)
+
+	c := b.Build()
+	scope := Get[ScopeFactory](c).CreateScope()
+	sc := scope.Container()
+
+	client := Get[*DisposableStruct](sc)
+	if Get[string](sc) != "client" || client.Value != 1 || calls != 1 {
+		t.Error("assertion failed")
+	}
+
+	if health := GetKeyed[[]bool](sc, "health"); len(health) != 2 || !health[0] || calls != 1 {
+		t.Error("assertion failed")
+	}
+
+	scope.Dispose()
+	if !client.Disposed {
+		t.Error("expect disposed")
+	}
+
+	if Get[*DisposableStruct](Get[ScopeFactory](c).CreateScope().Container()).Value != 2 {
+		t.Error("assertion failed")
+	}
+}
+
+type tenantResult struct {
+	Out
+	Name string `di:"group=tenant"`
+}
+
+func TestStruct_ResultObject_Registrations(t *testing.T) {
+	b := Builder()
+	AddSingleton[tenantResult](b, func() tenantResult { return tenantResult{Name: "a"} })
+	AddSingleton[tenantResult](b, func() tenantResult { return tenantResult{Name: "b"} })
+
+	c := b.Build()
+	names := GetKeyed[[]string](c, "tenant")
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("expect the fields resolved from their own registrations, actual %v", names)
+	}
+}
+
+func TestStruct_ResultObject_Transient(t *testing.T) {
+	calls := 0
+	b := Builder()
+	AddTransient[clientResult](b, func() clientResult {
+		calls++
+		return clientResult{Client: &DisposableStruct{Value: calls}, Name: "client"}
+	})
+
+	c := b.Build()
+	if Get[*DisposableStruct](c).Value != 1 || Get[string](c) != "client" || calls != 2 {
+		t.Error("expect a transient result object constructed for each of the fields resolved")
+	}
+}
@@ -26,6 +26,15 @@ func (m *Map[TK, TV]) Load(key TK) (TV, bool) {
 	return v2, ok
 }
 
+func (m *Map[TK, TV]) LoadAndDelete(key TK) (TV, bool) {
+	v, ok := m.data.LoadAndDelete(key)
+	var v2 TV
+	if ok {
+		v2, ok = v.(TV)
+	}
+	return v2, ok
+}
+
 func (m *Map[TK, TV]) LoadOrStore(key TK, value TV) (TV, bool) {
 	v, ok := m.data.LoadOrStore(key, value)
 	return v.(TV), ok
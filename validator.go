@@ -11,38 +11,42 @@ import (
 
 type validatorState struct {
 	Singleton CallSite
+	// the services resolved on demand being validated, they're skipped to break the circular dependencies.
+	onDemand map[ServiceIdentifier]bool
 }
 
 type CallSiteValidator struct {
-	scopedServices *syncx.Map[reflect.Type, reflect.Type]
+	scopedServices *syncx.Map[ServiceIdentifier, reflect.Type]
+	// the factory of the call sites of the services resolved on demand.
+	factory *CallSiteFactory
 }
 
-func (v *CallSiteValidator) ValidateCallSite(callSite CallSite) error {
+func (v *CallSiteValidator) ValidateCallSite(id ServiceIdentifier, callSite CallSite) error {
 	scoped, err := v.visitCallSite(callSite, validatorState{})
 	if err != nil {
 		return err
 	}
 
 	if scoped != nil {
-		v.scopedServices.Store(callSite.ServiceType(), scoped)
+		v.scopedServices.Store(id, scoped)
 	}
 
 	return nil
 }
 
-func (v *CallSiteValidator) ValidateResolution(serviceType reflect.Type, scope Scope, rootScope Scope) (err error) {
+func (v *CallSiteValidator) ValidateResolution(id ServiceIdentifier, scope Scope, rootScope Scope) (err error) {
 	if scope == rootScope {
-		scopedService, ok := v.scopedServices.Load(serviceType)
+		scopedService, ok := v.scopedServices.Load(id)
 		if !ok {
 			return
 		}
-		if serviceType == scopedService {
+		if id.ServiceType == scopedService {
 			return &errorx.ScopedServiceFromRootError{
-				Message: fmt.Sprintf("cannot resolve scoped service '%v' from root scope", serviceType)}
+				Message: fmt.Sprintf("cannot resolve scoped service '%v' from root scope", id)}
 		}
 
 		return &errorx.ScopedServiceFromRootError{
-			Message: fmt.Sprintf("cannot resolve '%v' from root scope because it requires scoped service '%v'", serviceType, scopedService),
+			Message: fmt.Sprintf("cannot resolve '%v' from root scope because it requires scoped service '%v'", id, scopedService),
 		}
 	}
 	return
@@ -58,6 +62,8 @@ func (r *CallSiteValidator) visitCallSite(callSite CallSite, state validatorStat
 		return r.visitDisposeCache(callSite, state)
 	case CacheLocation_None:
 		return r.visitNoCache(callSite, state)
+	case CacheLocation_Managed:
+		return r.visitManagedCache(callSite, state)
 	default:
 		return nil, errors.New("unknow cache location")
 	}
@@ -67,10 +73,26 @@ func (r *CallSiteValidator) visitCallSiteMain(callSite CallSite, state validator
 	switch callSite.Kind() {
 	case CallSiteKind_Factory, CallSiteKind_Constant, CallSiteKind_Container:
 		return nil, nil
+	case CallSiteKind_Lazy:
+		return r.visitOnDemand(callSite.(*LazyCallSite).Service, state)
+	case CallSiteKind_Provider:
+		return r.visitOnDemand(callSite.(*ProviderCallSite).Service, state)
 	case CallSiteKind_Slice:
 		return r.visitSlice(callSite.(*SliceCallSite), state)
 	case CallSiteKind_Constructor:
 		return r.visitConstructor(callSite.(*ConstructorCallSite), state)
+	case CallSiteKind_Struct:
+		return r.visitStruct(callSite.(*StructCallSite), state)
+	case CallSiteKind_Decorator:
+		return r.visitDecorator(callSite.(*DecoratorCallSite), state)
+	case CallSiteKind_Optional:
+		return r.visitOptional(callSite.(*OptionalCallSite), state)
+	case CallSiteKind_Assisted:
+		return r.visitAssisted(callSite.(*AssistedCallSite), state)
+	case CallSiteKind_ScopeLocal:
+		return r.visitScopeLocal(callSite.(*ScopeLocalCallSite), state)
+	case CallSiteKind_Activation:
+		return r.visitCallSite(callSite.(*ActivationCallSite).Inner, state)
 	default:
 		return nil, errors.New("unknow call site kind")
 	}
@@ -91,6 +113,76 @@ func (v *CallSiteValidator) visitConstructor(callSite *ConstructorCallSite, stat
 	return result, nil
 }
 
+func (v *CallSiteValidator) visitStruct(callSite *StructCallSite, state validatorState) (reflect.Type, error) {
+	var result reflect.Type
+	for _, cs := range callSite.Fields {
+		if cs == nil {
+			continue
+		}
+		scoped, err := v.visitCallSite(cs, state)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = scoped
+		}
+	}
+	return result, nil
+}
+
+func (v *CallSiteValidator) visitDecorator(callSite *DecoratorCallSite, state validatorState) (reflect.Type, error) {
+	result, err := v.visitCallSite(callSite.Inner, state)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cs := range callSite.Parameters {
+		scoped, err := v.visitCallSite(cs, state)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = scoped
+		}
+	}
+	return result, nil
+}
+
+// the scope-local services are validated as the scoped services.
+func (v *CallSiteValidator) visitScopeLocal(callSite *ScopeLocalCallSite, state validatorState) (reflect.Type, error) {
+	if state.Singleton != nil {
+		return nil, fmt.Errorf("cannot consume scope-local service '%v' from singleton '%v'",
+			callSite.ServiceType(),
+			state.Singleton.ServiceType())
+	}
+	return callSite.ServiceType(), nil
+}
+
+// only the container parameters of the assisted call site are validated.
+func (v *CallSiteValidator) visitAssisted(callSite *AssistedCallSite, state validatorState) (reflect.Type, error) {
+	var result reflect.Type
+	for _, cs := range callSite.Parameters {
+		if cs == nil {
+			continue
+		}
+		scoped, err := v.visitCallSite(cs, state)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = scoped
+		}
+	}
+	return result, nil
+}
+
+func (v *CallSiteValidator) visitOptional(callSite *OptionalCallSite, state validatorState) (reflect.Type, error) {
+	if callSite.Inner == nil {
+		return nil, nil
+	}
+	return v.visitCallSite(callSite.Inner, state)
+}
+
 func (v *CallSiteValidator) visitSlice(callSite *SliceCallSite, state validatorState) (reflect.Type, error) {
 	var result reflect.Type
 	for _, cs := range callSite.CallSites {
@@ -128,6 +220,39 @@ func (v *CallSiteValidator) visitScopeCache(scopedCallSite CallSite, state valid
 	return scopedCallSite.ServiceType(), nil
 }
 
+// the managed services are validated as the built-in lifetime reported by their manager.
+func (v *CallSiteValidator) visitManagedCache(callSite CallSite, state validatorState) (reflect.Type, error) {
+	switch callSite.Cache().Manager.Lifetime() {
+	case Lifetime_Singleton:
+		return v.visitRootCache(callSite, state)
+	case Lifetime_Scoped:
+		return v.visitScopeCache(callSite, state)
+	default:
+		return v.visitDisposeCache(callSite, state)
+	}
+}
+
+// the service resolved on demand is validated as a direct dependency,
+// as it's resolved from the scope that it's injected from.
+func (v *CallSiteValidator) visitOnDemand(service ServiceIdentifier, state validatorState) (reflect.Type, error) {
+	if state.onDemand[service] {
+		return nil, nil
+	}
+
+	callSite, err := v.factory.GetCallSiteByIdentifier(service, newCallSiteChain())
+	if err != nil {
+		return nil, err
+	}
+
+	if state.onDemand == nil {
+		state.onDemand = make(map[ServiceIdentifier]bool)
+	}
+	state.onDemand[service] = true
+	defer delete(state.onDemand, service)
+
+	return v.visitCallSite(callSite, state)
+}
+
 func (v *CallSiteValidator) visitDisposeCache(callSite CallSite, state validatorState) (reflect.Type, error) {
 	return v.visitCallSiteMain(callSite, state)
 }
@@ -136,6 +261,9 @@ func (v *CallSiteValidator) visitNoCache(callSite CallSite, state validatorState
 	return v.visitCallSiteMain(callSite, state)
 }
 
-func newCallSiteValidator() *CallSiteValidator {
-	return &CallSiteValidator{scopedServices: syncx.NewMap[reflect.Type, reflect.Type]()}
+func newCallSiteValidator(factory *CallSiteFactory) *CallSiteValidator {
+	return &CallSiteValidator{
+		scopedServices: syncx.NewMap[ServiceIdentifier, reflect.Type](),
+		factory:        factory,
+	}
 }
@@ -0,0 +1,168 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dozm/di/errorx"
+)
+
+// The result of the construction of a singleton service during Build.
+type WarmUpResult struct {
+	ServiceType reflect.Type
+	// the descriptor of the service, nil if the service is only a dependency of the eager services.
+	Descriptor *Descriptor
+	Duration   time.Duration
+	Err        error
+}
+
+// The call sites of the singleton descriptors selected by include,
+// the descriptors of the call sites are mapped by the call sites.
+func (c *container) singletonCallSites(include func(*Descriptor) bool) ([]CallSite, map[CallSite]*Descriptor, error) {
+	callSites := make([]CallSite, 0)
+	descriptors := make(map[CallSite]*Descriptor)
+	errs := &errorx.AggregateError{}
+	for _, d := range c.CallSiteFactory.Descriptors() {
+		if !d.isSingleton() || !include(d) {
+			continue
+		}
+
+		callSite, err := c.CallSiteFactory.GetCallSiteByDescriptor(d, newCallSiteChain())
+		if err == nil && c.isSharedSingleton(callSite) {
+			// the singleton is owned by the parent container.
+			continue
+		}
+		if err == nil && c.callSiteValidator != nil {
+			err = c.callSiteValidator.ValidateCallSite(d.Identifier(), callSite)
+		}
+		if err != nil {
+			errs.Add(annotateModule(d, err))
+			continue
+		}
+
+		callSites = append(callSites, callSite)
+		if _, ok := descriptors[callSite]; !ok {
+			descriptors[callSite] = d
+		}
+	}
+	return callSites, descriptors, errs.OrNil()
+}
+
+// annotate the error by the module that registered the descriptor,
+// unless it's annotated by the module already.
+func annotateModule(d *Descriptor, err error) error {
+	if d == nil || d.Module == "" {
+		return err
+	}
+
+	// annotated by the module of the failing dependency already.
+	var moduleErr *errorx.ModuleError
+	if errors.As(err, &moduleErr) {
+		return err
+	}
+	return &errorx.ModuleError{Module: d.Module, Err: err}
+}
+
+// Resolve the eager singleton services and their singleton dependencies,
+// the independent services are constructed concurrently,
+// a service is not constructed if any of its dependencies failed.
+func (c *container) warmUp(options Options) error {
+	roots, descriptors, err := c.singletonCallSites(func(d *Descriptor) bool {
+		return options.EagerSingletons || d.Eager
+	})
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		return nil
+	}
+
+	concurrency := options.WarmUpConcurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	callSites := sortSingletonCallSites(roots)
+	done := make(map[CallSite]chan struct{}, len(callSites))
+	for _, cs := range callSites {
+		done[cs] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	failed := make(map[CallSite]bool)
+	errs := &errorx.AggregateError{}
+	report := func(cs CallSite, d time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			failed[cs] = true
+			errs.Add(annotateModule(descriptors[cs], err))
+		}
+		if options.OnWarmUp != nil {
+			options.OnWarmUp(WarmUpResult{
+				ServiceType: cs.ServiceType(),
+				Descriptor:  descriptors[cs],
+				Duration:    d,
+				Err:         err,
+			})
+		}
+	}
+	dependencyFailed := func(cs CallSite) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dep := range singletonDependencies(cs) {
+			if failed[dep] {
+				failed[cs] = true
+				return true
+			}
+		}
+		return false
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, cs := range callSites {
+		wg.Add(1)
+		go func(cs CallSite) {
+			defer wg.Done()
+			defer close(done[cs])
+
+			for _, dep := range singletonDependencies(cs) {
+				<-done[dep]
+			}
+			if dependencyFailed(cs) || cs.Kind() == CallSiteKind_Constant || c.rootOf(cs) != c.Root {
+				return
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			err := c.resolveSingleton(cs)
+			report(cs, time.Since(start), err)
+		}(cs)
+	}
+	wg.Wait()
+
+	return errs.OrNil()
+}
+
+// resolve the singleton call site in the root scope, the panic is recovered as an error.
+func (c *container) resolveSingleton(callSite CallSite) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			if e, ok := p.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	_, err = CallSiteResolverInstance.Resolve(callSite, c.Root)
+	return
+}
@@ -0,0 +1,109 @@
+package di
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dozm/di/errorx"
+)
+
+type warmUpA struct{}
+type warmUpB struct{}
+type warmUpC struct{ a *warmUpA }
+
+func TestWarmUp(t *testing.T) {
+	var mu sync.Mutex
+	var count int32
+	results := make(map[string]WarmUpResult)
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.EagerSingletons = true
+		o.WarmUpConcurrency = 2
+		o.OnWarmUp = func(r WarmUpResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			results[r.ServiceType.String()] = r
+		}
+	})
+	AddSingleton[*warmUpA](b, func() *warmUpA {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &warmUpA{}
+	})
+	AddSingleton[*warmUpB](b, func() *warmUpB {
+		atomic.AddInt32(&count, 1)
+		return &warmUpB{}
+	})
+	AddSingleton[*warmUpC](b, func(a *warmUpA) *warmUpC {
+		atomic.AddInt32(&count, 1)
+		return &warmUpC{a: a}
+	})
+	AddTransient[int](b, func() int {
+		atomic.AddInt32(&count, 1)
+		return 1
+	})
+
+	c := b.Build()
+	if count != 3 || len(results) != 3 {
+		t.Fatalf("expect the singletons constructed during Build, actual %v", results)
+	}
+	if r := results["*di.warmUpA"]; r.Duration < 10*time.Millisecond || r.Descriptor == nil || r.Err != nil {
+		t.Error("expect the construction time reported")
+	}
+
+	if Get[*warmUpC](c).a != Get[*warmUpA](c) || count != 3 {
+		t.Error("expect the singletons resolved once")
+	}
+}
+
+func TestWarmUp_Eager(t *testing.T) {
+	count := 0
+	b := Builder()
+	AddSingleton[*warmUpA](b, func() *warmUpA {
+		count++
+		return &warmUpA{}
+	})
+	AddSingleton[*warmUpC](b, func(a *warmUpA) *warmUpC {
+		count++
+		return &warmUpC{a: a}
+	}, Eager())
+	AddSingleton[*warmUpB](b, func() *warmUpB {
+		count++
+		return &warmUpB{}
+	})
+
+	b.Build()
+	if count != 2 {
+		t.Error("expect only the eager singleton and its dependency constructed")
+	}
+}
+
+func TestWarmUp_Error(t *testing.T) {
+	constructed := false
+	b := Builder()
+	b.ConfigureOptions(func(o *Options) {
+		o.EagerSingletons = true
+	})
+	AddSingleton[*warmUpA](b, func() (*warmUpA, error) { return nil, errors.New("failed") })
+	AddSingleton[*warmUpB](b, func() (*warmUpB, error) { return nil, errors.New("failed") })
+	AddSingleton[*warmUpC](b, func(a *warmUpA) *warmUpC {
+		constructed = true
+		return &warmUpC{a: a}
+	})
+	sibling := &DisposableStruct{}
+	AddSingleton[*DisposableStruct](b, func() *DisposableStruct { return sibling })
+
+	defer func() {
+		err, ok := recover().(*errorx.AggregateError)
+		if !ok || len(err.Errors) != 2 || constructed {
+			t.Error("expect the errors aggregated and the dependents not constructed")
+		}
+		if !sibling.Disposed {
+			t.Error("expect the singletons built before the failure disposed")
+		}
+	}()
+	b.Build()
+}